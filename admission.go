@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AdmissionDenial represents a group of identical admission webhook denials
+type AdmissionDenial struct {
+	Webhook string
+	Engine  string // Policy engine inferred from the webhook name (Gatekeeper, Kyverno, OPA)
+	Policy  string // Constraint or policy name, when present
+	Reason  string
+	Count   int
+	Example string
+}
+
+var (
+	// `admission webhook "validation.gatekeeper.sh" denied the request: <reason>`
+	// (double quotes are replaced with single quotes when the log is read)
+	admissionDenialRegex = regexp.MustCompile(`admission webhook \\?["']([^"'\\]+)\\?["'] denied the request:?\s*(.*)`)
+
+	// Gatekeeper prefixes the reason with the constraint name, e.g. "[require-team-label] ..."
+	admissionPolicyRegex = regexp.MustCompile(`^\[([^\]]+)\]\s*(.*)$`)
+
+	// Kyverno reports "<policy>:\n  <rule>: 'validation error: ...'" flattened onto one line, after
+	// "resource violation" or "resource <kind>/<namespace>/<name> was blocked due to the following policies"
+	kyvernoPolicyRegex = regexp.MustCompile(`(?:resource violation|blocked due to the following policies):?\s*([\w.\-]+):\s*([\w.\-]+):\s*'?(.*?)'?$`)
+)
+
+// admissionEngines maps webhook names to the policy engine serving them
+var admissionEngines = []struct {
+	engine string
+	re     *regexp.Regexp
+}{
+	{"Gatekeeper", regexp.MustCompile(`(?i)gatekeeper`)},
+	{"Kyverno", regexp.MustCompile(`(?i)kyverno`)},
+	{"OPA", regexp.MustCompile(`(?i)\bopa\b|openpolicyagent`)},
+}
+
+// Function to extract admission webhook denials from the log content, grouped by webhook, policy and reason
+func extractAdmissionDenials(content string) []AdmissionDenial {
+	var denials []AdmissionDenial
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		matches := admissionDenialRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		webhook := matches[1]
+		policy, reason := parseAdmissionReason(matches[2])
+
+		key := webhook + "|" + policy + "|" + reason
+		if i, ok := index[key]; ok {
+			denials[i].Count++
+			continue
+		}
+
+		index[key] = len(denials)
+		denials = append(denials, AdmissionDenial{
+			Webhook: webhook,
+			Engine:  admissionEngine(webhook),
+			Policy:  policy,
+			Reason:  reason,
+			Count:   1,
+			Example: line,
+		})
+	}
+
+	return denials
+}
+
+// Helper function to split a denial message into the policy name (when present) and the human reason
+func parseAdmissionReason(message string) (string, string) {
+	// Strip quoting left over from structured log fields
+	message = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(message), `"'`))
+	message = strings.ReplaceAll(message, `\n`, " ")
+
+	if matches := admissionPolicyRegex.FindStringSubmatch(message); matches != nil {
+		return matches[1], strings.TrimSpace(matches[2])
+	}
+	if matches := kyvernoPolicyRegex.FindStringSubmatch(message); matches != nil {
+		return matches[1] + "/" + matches[2], strings.TrimSpace(matches[3])
+	}
+	return "", message
+}
+
+// Helper function to infer the policy engine from the webhook name
+func admissionEngine(webhook string) string {
+	for _, engine := range admissionEngines {
+		if engine.re.MatchString(webhook) {
+			return engine.engine
+		}
+	}
+	return "other"
+}
+
+// Function to render the admission denials as a Markdown table
+func renderAdmissionDenials(denials []AdmissionDenial) string {
+	var sb strings.Builder
+	sb.WriteString("| Webhook | Engine | Policy | Reason | Occurrences |\n")
+	sb.WriteString("|---------|--------|--------|--------|-------------|\n")
+	for _, denial := range denials {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %d |\n",
+			denial.Webhook, denial.Engine, tableCell(valueOrDash(denial.Policy), 60),
+			tableCell(valueOrDash(denial.Reason), 160), denial.Count))
+	}
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractAdmissionDenials(t *testing.T) {
+	content := `Error from server (Forbidden): error when creating 'deploy.yaml': admission webhook 'validation.gatekeeper.sh' denied the request: [require-team-label] you must provide labels: {'team'}
+Error from server (Forbidden): error when creating 'deploy.yaml': admission webhook 'validation.gatekeeper.sh' denied the request: [require-team-label] you must provide labels: {'team'}
+E0102 replicaset controller: admission webhook \'validate.kyverno.svc-fail\' denied the request: resource Deployment/shop/web was blocked due to the following policies\n\nrequire-requests-limits:\n  validate-resources: 'validation error: CPU and memory resource requests and limits are required. rule validate-resources failed at path /spec/template/spec/containers/0/resources/limits/'
+admission webhook 'policy.example.com' denied the request: images from docker.io are not allowed`
+
+	denials := extractAdmissionDenials(content)
+	for i := range denials {
+		denials[i].Example = "" // Only the grouping is checked here
+	}
+	want := []AdmissionDenial{
+		{Webhook: "validation.gatekeeper.sh", Engine: "Gatekeeper", Policy: "require-team-label", Reason: "you must provide labels: {'team'}", Count: 2},
+		{Webhook: "validate.kyverno.svc-fail", Engine: "Kyverno", Policy: "require-requests-limits/validate-resources", Reason: "validation error: CPU and memory resource requests and limits are required. rule validate-resources failed at path /spec/template/spec/containers/0/resources/limits/", Count: 1},
+		{Webhook: "policy.example.com", Engine: "other", Reason: "images from docker.io are not allowed", Count: 1},
+	}
+	if !reflect.DeepEqual(denials, want) {
+		t.Errorf("extractAdmissionDenials =\n%+v\nwant\n%+v", denials, want)
+	}
+}
+
+func TestParseAdmissionReasonKyvernoViolation(t *testing.T) {
+	policy, reason := parseAdmissionReason(`resource violation: require-labels: check-team: 'label team is required'`)
+	if policy != "require-labels/check-team" || reason != "label team is required" {
+		t.Errorf("parseAdmissionReason = %q, %q; want %q, %q", policy, reason, "require-labels/check-team", "label team is required")
+	}
+}
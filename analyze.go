@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// OutputNameData holds the values available to -output-name-template
+type OutputNameData struct {
+	Name  string // Log file name, e.g. "01-LOG-HIGH.log"
+	Base  string // Log file name without its extension, e.g. "01-LOG-HIGH"
+	Ext   string // Extension of the output format, e.g. ".md" or ".json"
+	Index int    // 1-based position of the log among the matched files
+}
+
+// Function to run the non-interactive analysis and save it as Markdown.
+// With -output-dir every matching log is analyzed into its own file; otherwise only the first match is.
+func runAnalyze(ctx context.Context, opts *Options) error {
+	if err := validateLokiOptions(opts.Loki); err != nil {
+		return err
+	}
+
+	// Collapsing duplicates needs a roll-up, one row per file unless grouped otherwise
+	if opts.DedupeAcrossFiles && opts.GroupBy == "" {
+		opts.GroupBy = "file"
+	}
+	if opts.GroupBy != "" {
+		if err := validateGroupBy(opts.GroupBy); err != nil {
+			return err
+		}
+		if opts.OutputDir == "" {
+			return fmt.Errorf("Error: -group-by and -dedupe-across-files require -output-dir")
+		}
+	}
+	if opts.MergeOutput != "" && opts.OutputDir == "" {
+		return fmt.Errorf("Error: -merge-output requires -output-dir")
+	}
+	severityThreshold, err := parseSeverityThreshold(opts.FailOnSeverity)
+	if err != nil {
+		return err
+	}
+	if opts.OutputDiff != "" && opts.OutputDir != "" {
+		return fmt.Errorf("Error: -output-diff compares a single analysis and cannot be used with -output-dir")
+	}
+
+	// The follow-up passes need the model analysis
+	if opts.ClassifyOnly && (opts.DeepDive || opts.AnalyzeJSON || opts.ValidateOutput) {
+		return fmt.Errorf("Error: -classify-only cannot be used with -deep-dive, -analyze-json or -validate-output")
+	}
+
+	if _, err := parseWebhookOptions(opts.Webhook, opts.WebhookHeaders); err != nil {
+		return err
+	}
+
+	formats, err := parseFormats(formatsValue(opts))
+	if err != nil {
+		return err
+	}
+
+	outputEncoding, err := parseOutputEncoding(opts.OutputEncoding)
+	if err != nil {
+		return err
+	}
+
+	requestOptions, err := newRequestOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	runMetadata, err := newRunMetadata(opts, requestOptions.Redactions)
+	if err != nil {
+		return err
+	}
+	defer runMetadata.Close()
+
+	fileList, err := findLogFiles(opts.LogPattern)
+	if err != nil {
+		return err
+	}
+
+	// Single file mode: analyze the first match into -output
+	if opts.OutputDir == "" {
+		targets, err := buildOutputTargets(opts.OutputFile, formats, outputEncoding)
+		if err != nil {
+			return err
+		}
+		for i := range targets {
+			targets[i].Redactions = requestOptions.Redactions
+			targets[i].StripANSI = shouldStripANSI(opts.StripANSI, targets[i].Path)
+		}
+		logFile, err := readLog(fileList[0], opts.SinceFile)
+		if err != nil {
+			if opts.OutputOnError {
+				writeFailedReport(AnalysisReport{LogFile: fileList[0], Metadata: runMetadata.Fork()}, targets, err)
+			}
+			return err
+		}
+		report, err := analyzeLog(ctx, opts, requestOptions, runMetadata, logFile, targets)
+		if err != nil {
+			return err
+		}
+		printCostSummary(runMetadata)
+
+		// Fail the CI job when the log shows problems at or above the threshold
+		if report != nil {
+			return checkSeverityGate(severityThreshold, []AnalysisReport{*report})
+		}
+		return nil
+	}
+
+	// Guard against an overly broad pattern triggering a huge (and expensive) batch
+	if err := confirmFileCount(fileList, opts); err != nil {
+		return err
+	}
+
+	// Batch mode: analyze every match into a templated file in -output-dir
+	nameTemplate, err := template.New("output-name").Option("missingkey=error").Parse(opts.OutputNameTemplate)
+	if err != nil {
+		return fmt.Errorf("Error parsing -output-name-template: %v", err)
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory %s: %v", opts.OutputDir, err)
+	}
+
+	var failed []string
+	var rollup []RollupEntry
+	var reports []AnalysisReport
+	for i, path := range fileList {
+		var targets []OutputTarget
+		var logFile LogFile
+		var report *AnalysisReport
+		for _, format := range formats {
+			var outputPath string
+			outputPath, err = buildOutputPath(nameTemplate, opts.OutputDir, path, i+1, outputFormatExtensions[format])
+			if err != nil {
+				break
+			}
+			targets = append(targets, OutputTarget{Format: format, Path: outputPath, Encoding: outputEncoding, Redactions: requestOptions.Redactions, StripANSI: shouldStripANSI(opts.StripANSI, outputPath)})
+		}
+		if err == nil {
+			logFile, err = readLog(path, opts.SinceFile)
+			if err == nil {
+				report, err = analyzeLog(ctx, opts, requestOptions, runMetadata, logFile, targets)
+			} else if opts.OutputOnError {
+				writeFailedReport(AnalysisReport{LogFile: path, Metadata: runMetadata.Fork()}, targets, err)
+			}
+		}
+		if report != nil && (opts.MergeOutput != "" || severityThreshold != "") {
+			reports = append(reports, *report)
+		}
+		if opts.GroupBy != "" {
+			logFile.Path = path
+			rollup = append(rollup, newRollupEntry(opts.GroupBy, logFile, targets, report, err))
+		}
+
+		// Ctrl+C stops the batch, while one bad log does not
+		if ctx.Err() != nil {
+			printCostSummary(runMetadata)
+			return fmt.Errorf("Batch interrupted after %d of %d logs: %w", i, len(fileList), ctx.Err())
+		}
+
+		// Keep going so one bad log does not stop the whole batch
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error analyzing %s: %v\n", path, err)
+			failed = append(failed, path)
+		}
+	}
+
+	// Summarize the batch grouped by namespace, pod or file
+	if opts.GroupBy != "" {
+		if opts.DedupeAcrossFiles {
+			rollup = dedupeRollup(rollup)
+		}
+		if err := writeRollup(rollup, opts.GroupBy, opts.OutputDir); err != nil {
+			return err
+		}
+	}
+
+	// Combine the analyses into one document next to the per-file outputs
+	if opts.MergeOutput != "" && len(reports) > 0 {
+		if err := writeMergedReport(reports, opts.MergeOutput, outputEncoding, requestOptions.Redactions, shouldStripANSI(opts.StripANSI, opts.MergeOutput)); err != nil {
+			return err
+		}
+	}
+
+	printCostSummary(runMetadata)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Error: %d of %d logs failed to analyze", len(failed), len(fileList))
+	}
+	return checkSeverityGate(severityThreshold, reports)
+}
+
+// Function to check the number of logs matched in batch mode against -max-files,
+// asking for confirmation on a terminal and requiring -yes otherwise
+func confirmFileCount(fileList []string, opts *Options) error {
+	if opts.MaxFiles <= 0 || len(fileList) <= opts.MaxFiles || opts.AssumeYes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("Error: -log %q matched %d files, above the -max-files limit of %d; narrow the pattern, raise -max-files or pass -yes", opts.LogPattern, len(fileList), opts.MaxFiles)
+	}
+	if !confirm(fmt.Sprintf("-log %q matched %d files (limit %d), analyze all of them? [y/N] ", opts.LogPattern, len(fileList), opts.MaxFiles)) {
+		return fmt.Errorf("Analysis cancelled: %d matched files were not confirmed", len(fileList))
+	}
+	return nil
+}
+
+// Function to build the output path of a log in batch mode from the name template
+func buildOutputPath(nameTemplate *template.Template, outputDir, logPath string, index int, ext string) (string, error) {
+	name := filepath.Base(logPath)
+	data := OutputNameData{
+		Name:  name,
+		Base:  strings.TrimSuffix(name, filepath.Ext(name)),
+		Ext:   ext,
+		Index: index,
+	}
+
+	var sb strings.Builder
+	if err := nameTemplate.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("Error executing -output-name-template: %v", err)
+	}
+
+	// Keep every output inside the output directory
+	outputName := sb.String()
+	if outputName == "" || strings.ContainsAny(outputName, `/\`) {
+		return "", fmt.Errorf("Error: -output-name-template produced an invalid file name %q", outputName)
+	}
+
+	return filepath.Join(outputDir, outputName), nil
+}
+
+// Function to analyze a single log and save the result to each output target.
+// The report is nil when the log was skipped because it has no new content.
+func analyzeLog(ctx context.Context, opts *Options, requestOptions RequestOptions, runMetadata *RunMetadata, logFile LogFile, targets []OutputTarget) (*AnalysisReport, error) {
+	// Nothing to do when the log has not grown since the last run
+	if strings.TrimSpace(logFile.Content) == "" && logFile.since != nil {
+		progressf("No new content in %s since the last run\n", logFile.Path)
+		return nil, nil
+	}
+
+	// Track the requested and server-reported models for each request
+	metadata := runMetadata.Fork()
+
+	// Collect the partial results, written with -output-on-error when a step fails
+	partial := AnalysisReport{LogFile: logFile.Path, LocalOnly: opts.ClassifyOnly, Metadata: metadata}
+	fail := func(err error) (*AnalysisReport, error) {
+		if opts.OutputOnError {
+			writeFailedReport(partial, targets, err)
+		}
+		return nil, err
+	}
+
+	// Focus on the current run of a crash-looping container
+	logFile, err := applySinceRestart(opts, applyCRIFormat(opts, logFile))
+	if err != nil {
+		return fail(err)
+	}
+	logString := logFile.Content
+
+	detectorOptions, err := newDetectorOptions(opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	webhook, err := parseWebhookOptions(opts.Webhook, opts.WebhookHeaders)
+	if err != nil {
+		return fail(err)
+	}
+
+	related, err := loadRelatedLogs(opts.AlsoLogs, logFile.Path)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Run the local detectors so their findings can guide the analysis (or make up the report with -classify-only)
+	findings := detectFindings(logString, detectorOptions)
+
+	// Correlate events by pod/container, only useful when the log covers several of them
+	timeline := buildEventIndex(logString, detectorOptions)
+	if len(timeline) < 2 {
+		timeline = nil
+	}
+	partial.Findings, partial.Timeline = findings, timeline
+
+	// Ask for translations of quoted lines when the log is not in English
+	if opts.DetectLanguage {
+		language := detectLanguage(logString)
+		metadata.Language = &language
+		verbosef("Detected language of %s: %s (confidence %.2f)", logFile.Path, language.Name, language.Confidence)
+	}
+
+	// The model passes are skipped with -classify-only, leaving the local detection as the report
+	var assistantResponseFirst, analysisResponse string
+	var containers []ContainerLog
+	var structured *StructuredAnalysis
+	var deepDives []DeepDive
+	if !opts.ClassifyOnly {
+		// Include the related logs as labeled sections after the primary log
+		keyPointsInput := KeyPointsInput{Primary: logString, Related: renderRelatedLogs(related, opts.AlsoLogTokens)}
+		if opts.IncludeLineNumbers {
+			keyPointsInput.Introduction, keyPointsInput.Primary = lineNumbersIntroduction, numberLines(logString, logFile.LineOffset)
+		}
+
+		// Enrich the analysis input with surrounding context from Loki.
+		// The enrichment is best effort, so a Loki failure does not stop the analysis (but Ctrl+C does).
+		var lokiContext string
+		if opts.Loki.Prepend {
+			lokiContext, err = fetchLokiContext(ctx, logContents(logFile, related), logFile.Window, opts.Loki)
+			if err != nil && ctx.Err() != nil {
+				return fail(err)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping Loki context for %s: %v\n", logFile.Path, err)
+				lokiContext = ""
+			} else if lokiContext == "" {
+				verbosef("Loki returned no context for %s", logFile.Path)
+			} else {
+				keyPointsInput.Loki = lokiContext
+			}
+		}
+
+		// -------------- First Request: Generate Key Points --------------
+		assistantResponseFirst, containers, err = generateLogKeyPoints(ctx, opts, keyPointsInput, requestOptions, metadata)
+		if err != nil {
+			return fail(err)
+		}
+		partial.KeyPoints, partial.Containers = assistantResponseFirst, containers
+
+		// -------------- Second Request: Perform Full Analysis --------------
+		requestOptions := withModel(requestOptions, opts.AnalysisModel)
+
+		// Prepare the analysis messages
+		prompt, err := renderSystemPrompt(requestOptions, logFile.Path, findings)
+		if err != nil {
+			return fail(err)
+		}
+
+		analysisMessages := newConversation(prompt, Message{
+			Role:    "user",
+			Content: keyPointsIntroduction(assistantResponseFirst, containers) + renderFindingsForPrompt(findings) + renderEventIndexForPrompt(timeline) + renderLanguageForPrompt(metadata.Language),
+		})
+		if opts.Checklist && !opts.AnalyzeJSON {
+			analysisMessages[len(analysisMessages)-1].Content += checklistPrompt
+		}
+		if opts.IncludeLineNumbers {
+			analysisMessages[len(analysisMessages)-1].Content += lineNumbersPrompt
+		}
+
+		// Estimate how much each prompt component contributes to the input tokens
+		if opts.TokenBreakdown {
+			keyPoints := keyPointsIntroduction(assistantResponseFirst, containers)
+			metadata.TokenBreakdown = tokenBreakdown(
+				TokenComponent{Component: "System prompt", Tokens: estimateTokens(prompt)},
+				TokenComponent{Component: "Key points instructions", Tokens: estimateTokens(keyPointsPrompt)},
+				TokenComponent{Component: "Log content", Tokens: estimateTokens(keyPointsInput.Introduction + keyPointsInput.Primary)},
+				TokenComponent{Component: "Related logs", Tokens: estimateTokens(keyPointsInput.Related)},
+				TokenComponent{Component: "Loki context", Tokens: estimateTokens(lokiContext)},
+				TokenComponent{Component: "Key points", Tokens: estimateTokens(keyPoints)},
+				TokenComponent{Component: "Findings and instructions", Tokens: estimateTokens(strings.TrimPrefix(analysisMessages[len(analysisMessages)-1].Content, keyPoints))},
+			)
+			verbosef("Token breakdown for %s: %s", logFile.Path, summarizeTokenBreakdown(metadata.TokenBreakdown))
+		}
+
+		// Request the analysis as schema-validated JSON, rendered to Markdown for the document formats
+		if opts.AnalyzeJSON {
+			analysis, err := requestStructuredAnalysis(ctx, analysisMessages, requestOptions, metadata)
+			if err != nil {
+				return fail(err)
+			}
+			structured = &analysis
+			analysisResponse = renderStructuredAnalysis(analysis)
+		} else {
+			// Send the analysis request
+			analysisResult, err := sendRequest(ctx, analysisMessages, requestOptions)
+			if err != nil {
+				return fail(err)
+			}
+			metadata.Record("Analysis", requestOptions.Model, analysisResult)
+			analysisResponse = analysisResult.Content
+		}
+
+		// Validate the analysis structure and re-prompt once if sections are missing
+		if opts.ValidateOutput && !opts.AnalyzeJSON {
+			missing := validateAnalysisOutput(analysisResponse)
+			if len(missing) == 0 {
+				verbosef("Output validation passed")
+			} else {
+				verbosef("Output validation failed, missing: %s", strings.Join(missing, "; "))
+
+				analysisMessages = append(analysisMessages,
+					Message{Role: "assistant", Content: analysisResponse},
+					Message{Role: "user", Content: buildValidationRetryPrompt(missing)},
+				)
+
+				retryResult, err := sendRequest(ctx, analysisMessages, requestOptions)
+				if err != nil {
+					return fail(err)
+				}
+				metadata.Record("Analysis (validation retry)", requestOptions.Model, retryResult)
+				analysisResponse = retryResult.Content
+
+				if missing := validateAnalysisOutput(analysisResponse); len(missing) > 0 {
+					verbosef("Output validation still failing after re-prompt, missing: %s", strings.Join(missing, "; "))
+				} else {
+					verbosef("Output validation passed after re-prompt")
+				}
+			}
+		}
+
+		// Make sure every recommendation is a checkbox, even if the model did not comply
+		if opts.Checklist {
+			analysisResponse = applyChecklist(analysisResponse)
+		}
+		partial.Analysis, partial.Structured = analysisResponse, structured
+
+		// Follow up on each detected issue with a focused remediation runbook
+		if opts.DeepDive {
+			deepDives, err = requestDeepDives(ctx, findings, analysisMessages, analysisResponse, requestOptions, metadata)
+			if err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	// Generate the Loki queries, rendered as curl commands in the Markdown report
+	lokiQueries := generateLokiQueries(logContents(logFile, related), logFile.Window, opts.Loki)
+
+	// Build the report once so every output format carries the same content
+	report := AnalysisReport{
+		LogFile:     logFile.Path,
+		LocalOnly:   opts.ClassifyOnly,
+		LineNumbers: opts.IncludeLineNumbers,
+		KeyPoints:   assistantResponseFirst,
+		Analysis:    analysisResponse,
+		Structured:  structured,
+		Containers:  containers,
+		Findings:    findings,
+		DeepDives:   deepDives,
+		Timeline:    timeline,
+		TraceIDs:    extractTraceIDs(logString),
+		TraceURL:    opts.TraceURL,
+		LokiQueries: lokiQueries,
+		LokiPretty:  opts.Loki.PrettyCurl,
+		Metadata:    metadata,
+	}
+
+	// Compare with the previous run's output before it is overwritten
+	if opts.OutputDiff != "" {
+		report.Diff, err = diffAgainstPrevious(opts.OutputDiff, report)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	partial = report
+
+	// Execute the generated Loki queries
+	if opts.Loki.Run {
+		report.LokiResults, err = collectLokiResults(ctx, lokiQueries, opts.Loki.Concurrency)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := writeReport(report, targets); err != nil {
+		return nil, err
+	}
+
+	// Feed the completed analysis to an event-driven integration
+	deliverWebhook(ctx, webhook, requestOptions, report)
+
+	// Record the processed position only after a successful run
+	if logFile.since != nil {
+		if err := saveSinceMarker(opts.SinceFile, logFile.Path, *logFile.since); err != nil {
+			return nil, err
+		}
+	}
+	return &report, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// stripANSIModes are the accepted -strip-ansi values. "auto" strips the output written to files
+// and piped stdout, but keeps the escape sequences when the output goes to a terminal.
+var stripANSIModes = map[string]bool{"auto": true, "always": true, "never": true}
+
+// Function to validate the -strip-ansi mode
+func parseStripANSI(mode string) error {
+	if !stripANSIModes[mode] {
+		return fmt.Errorf("Error: invalid -strip-ansi %q: use auto, always or never", mode)
+	}
+	return nil
+}
+
+// Helper function to decide whether the output written to path ("-" for stdout) is stripped
+func shouldStripANSI(mode, path string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		return path != "-" || !isTerminal(os.Stdout)
+	}
+}
+
+// Function to remove any residual ANSI escape sequences, e.g. echoed by the model, from a text
+func stripANSI(text string) string {
+	return ansi.Strip(text)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// StreamBenchmark holds the responsiveness of a streamed request, measured with -benchmark
+type StreamBenchmark struct {
+	TimeToFirstToken time.Duration `json:"time_to_first_token_ns"`
+	Total            time.Duration `json:"total_ns"`
+	Chunks           int           `json:"chunks"`
+	Tokens           int           `json:"tokens"` // Estimated from the response length
+
+	firstToken time.Time
+}
+
+// Function to compute the durations of the benchmark from the start of the request
+func (b *StreamBenchmark) Finish(start time.Time, content string) {
+	b.Total = time.Since(start)
+	if !b.firstToken.IsZero() {
+		b.TimeToFirstToken = b.firstToken.Sub(start)
+	}
+	b.Tokens = estimateTokens(content)
+}
+
+// Function to approximate the generation throughput, from the first token to the end of the stream
+func (b StreamBenchmark) TokensPerSecond() float64 {
+	generation := (b.Total - b.TimeToFirstToken).Seconds()
+	if generation <= 0 {
+		return 0
+	}
+	return float64(b.Tokens) / generation
+}
+
+// Function to format the benchmark as a one-line report
+func (b StreamBenchmark) Summary() string {
+	return fmt.Sprintf("Benchmark: time to first token %s, ~%.1f tokens/s (~%d tokens in %d chunks), total latency %s",
+		b.TimeToFirstToken.Round(time.Millisecond), b.TokensPerSecond(), b.Tokens, b.Chunks, b.Total.Round(time.Millisecond))
+}
+
+// BenchmarkTotals accumulates the benchmarks of every streamed request in the run
+type BenchmarkTotals struct {
+	Requests         int
+	TimeToFirstToken time.Duration
+	Total            time.Duration
+	Generation       time.Duration
+	Tokens           int
+}
+
+// Function to add a request's benchmark to the totals
+func (t *BenchmarkTotals) Add(b StreamBenchmark) {
+	t.Requests++
+	t.TimeToFirstToken += b.TimeToFirstToken
+	t.Total += b.Total
+	t.Generation += b.Total - b.TimeToFirstToken
+	t.Tokens += b.Tokens
+}
+
+// Function to format the averages across the run as a one-line summary
+func (t *BenchmarkTotals) Summary() string {
+	throughput := 0.0
+	if t.Generation > 0 {
+		throughput = float64(t.Tokens) / t.Generation.Seconds()
+	}
+	average := func(d time.Duration) time.Duration {
+		return (d / time.Duration(t.Requests)).Round(time.Millisecond)
+	}
+	return fmt.Sprintf("Benchmark: %d streamed requests, average time to first token %s, ~%.1f tokens/s, average total latency %s",
+		t.Requests, average(t.TimeToFirstToken), throughput, average(t.Total))
+}
+
+// Function to print a benchmark after its stream completes.
+// It is a diagnostic, so it moves to stderr rather than being dropped with -quiet.
+func printBenchmark(b StreamBenchmark) {
+	if quiet {
+		fmt.Fprintf(os.Stderr, "%s\n", b.Summary())
+		return
+	}
+	progressf("\n%s\n", b.Summary())
+}
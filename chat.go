@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// chatTurnsWarning is how many turns before -max-turns the user is warned
+const chatTurnsWarning = 2
+
+// ChatSession holds the state of an interactive troubleshooting session
+type ChatSession struct {
+	messages       []Message
+	requestOptions RequestOptions
+	metadata       *RunMetadata
+	turn           int
+
+	// Re-print each question before its response so a captured transcript is self-contained
+	echoPrompt bool
+
+	// End the session after this many turns (0 for no limit)
+	maxTurns int
+
+	// The -strip-ansi mode applied when the transcript is saved
+	stripANSI string
+
+	// Condense the oldest half of the conversation above this many estimated tokens (0 disables),
+	// keeping every turn in history so the transcript stays complete
+	summarizeTokens int
+	summaries       int
+	history         []Message
+
+	// Drop the oldest messages above this many estimated tokens (0 disables), keeping the system prompt
+	maxHistoryTokens int
+
+	// Propose follow-up questions after each response, which can be asked by typing their number
+	suggest     bool
+	suggestions []string
+
+	// Context shown at the top of the transcript, and the number of messages seeding the conversation
+	logPath   string
+	keyPoints string
+	seeded    int
+
+	// Each exchange is appended to the -save-session file as the conversation proceeds
+	sessionLog *SessionLog
+}
+
+// Function to run the interactive troubleshooting session
+func runChat(ctx context.Context, opts *Options) error {
+	requestOptions, err := newRequestOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	// Continue a saved session instead of starting from a log
+	if opts.LoadSession != "" {
+		return runLoadedSession(ctx, opts, requestOptions)
+	}
+
+	// Without a log, answer a single general question using just the system prompt
+	if opts.LogPattern == "" {
+		return runQuestion(ctx, opts, requestOptions)
+	}
+
+	detectorOptions, err := newDetectorOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	logFile, err := loadLog(opts)
+	if err != nil {
+		return err
+	}
+	logString := logFile.Content
+
+	related, err := loadRelatedLogs(opts.AlsoLogs, logFile.Path)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := newRunMetadata(opts, requestOptions.Redactions)
+	if err != nil {
+		return err
+	}
+	defer metadata.Close()
+	defer printCostSummary(metadata)
+
+	// -------------- First Request: Generate Key Points --------------
+	keyPointsInput := KeyPointsInput{Primary: logString, Related: renderRelatedLogs(related, opts.AlsoLogTokens)}
+	if opts.IncludeLineNumbers {
+		keyPointsInput.Introduction, keyPointsInput.Primary = lineNumbersIntroduction, numberLines(logString, logFile.LineOffset)
+	}
+	assistantResponseFirst, containers, err := generateLogKeyPoints(ctx, opts, keyPointsInput, requestOptions, metadata)
+	if err != nil {
+		return err
+	}
+
+	// Run the local detectors so their findings can guide the session
+	findings := detectFindings(logString, detectorOptions)
+
+	prompt, err := renderSystemPrompt(requestOptions, logFile.Path, findings)
+	if err != nil {
+		return err
+	}
+
+	// Ask for line citations when the log was sent with line numbers
+	introduction := keyPointsIntroduction(assistantResponseFirst, containers) + renderFindingsForPrompt(findings) + renderEventIndexForPrompt(buildEventIndex(logString, detectorOptions))
+	if opts.IncludeLineNumbers {
+		introduction += lineNumbersPrompt
+	}
+
+	// Initialize messages for interactive session
+	session := newChatSession(opts, requestOptions, metadata, newConversation(prompt, Message{
+		Role:    "user",
+		Content: introduction,
+	}))
+	session.logPath = logFile.Path
+	session.keyPoints = assistantResponseFirst
+	return session.Run(ctx, opts)
+}
+
+// Function to create an interactive session seeded with the given messages
+func newChatSession(opts *Options, requestOptions RequestOptions, metadata *RunMetadata, messages []Message) *ChatSession {
+	session := &ChatSession{
+		messages:       messages,
+		requestOptions: withModel(requestOptions, opts.AnalysisModel),
+		metadata:       metadata,
+		echoPrompt:     opts.EchoPrompt,
+		maxTurns:       opts.MaxTurns,
+		stripANSI:      opts.StripANSI,
+		seeded:         len(messages),
+		suggest:        opts.Suggest,
+
+		maxHistoryTokens: opts.MaxHistoryTokens,
+	}
+	if opts.AutoSummarize {
+		session.summarizeTokens = opts.SummarizeTokens
+	}
+	return session
+}
+
+// Function to run the session: the seeded -ask question, then the user's messages until exit
+func (s *ChatSession) Run(ctx context.Context, opts *Options) error {
+	// Save the conversation however the session ends
+	defer s.SaveTranscript(opts.Transcript)
+
+	// Record the session as it proceeds
+	var err error
+	s.sessionLog, err = openSessionLog(opts.SaveSession, s.requestOptions.Redactions, opts.StripANSI)
+	if err != nil {
+		return err
+	}
+	defer s.sessionLog.Close()
+	if err := s.sessionLog.Start(s.messages, s.logPath, s.keyPoints); err != nil {
+		return err
+	}
+
+	// Start with the seeded question before handing over to the user
+	if opts.Ask != "" {
+		if !s.echoPrompt {
+			progressf("\n> %s\n", opts.Ask)
+		}
+		if err := s.SendTurn(ctx, opts.Ask); err != nil {
+			return err
+		}
+	}
+	if s.LimitReached() {
+		return nil
+	}
+
+	// Start interactive chat session, batching pasted lines into a single message
+	// Piped input arrives all at once, so the timing heuristic only applies to terminals
+	debounce := time.Duration(opts.PasteDebounceMs) * time.Millisecond
+	if !isTerminal(os.Stdin) {
+		debounce = 0
+	}
+	defer enableBracketedPaste()()
+	messages := readUserMessages(os.Stdin, debounce)
+	progressf("\nEnter your message (type 'exit' to quit, '/help' for commands):\n")
+	for {
+		progressf("> ")
+		userInput, ok := <-messages
+		if !ok {
+			break
+		}
+
+		// Check for exit command
+		if strings.ToLower(strings.TrimSpace(userInput)) == "exit" {
+			progressf("Exiting chat session.\n")
+			break
+		}
+
+		// Slash commands control the session and are never sent to the model
+		if handled, err := s.handleSlashCommand(ctx, userInput); handled {
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				break
+			}
+			if s.LimitReached() {
+				break
+			}
+			continue
+		}
+
+		// A number picks one of the suggested follow-up questions
+		if suggestion := s.pickSuggestion(userInput); suggestion != "" {
+			userInput = suggestion
+			if !s.echoPrompt {
+				progressf("> %s\n", userInput)
+			}
+		}
+
+		if err := s.SendTurn(ctx, userInput); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			break
+		}
+		if s.LimitReached() {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Function to check whether the session reached -max-turns, warning as the limit approaches
+func (s *ChatSession) LimitReached() bool {
+	if s.maxTurns <= 0 {
+		return false
+	}
+
+	remaining := s.maxTurns - s.turn
+	switch {
+	case remaining <= 0:
+		fmt.Fprintf(os.Stderr, "\nReached the -max-turns limit of %d, ending the session.\n", s.maxTurns)
+		return true
+	case remaining <= chatTurnsWarning:
+		fmt.Fprintf(os.Stderr, "\nWarning: %d of %d turns left (-max-turns).\n", remaining, s.maxTurns)
+	}
+	return false
+}
+
+// Function to render the session as Markdown: the key points, then each question and answer
+func (s *ChatSession) Transcript() string {
+	var sb strings.Builder
+	sb.WriteString("# Chat Transcript\n\n")
+	if s.logPath != "" {
+		sb.WriteString(fmt.Sprintf("**Log**: %s\n\n", s.logPath))
+	}
+	if s.keyPoints != "" {
+		sb.WriteString("## Key Points\n\n" + s.keyPoints + "\n\n")
+	}
+
+	turn := 0
+	for _, message := range s.history {
+		switch message.Role {
+		case "user":
+			turn++
+			sb.WriteString(fmt.Sprintf("## Turn %d\n\n%s\n\n", turn, quotePrompt(message.Content)))
+		case "assistant":
+			sb.WriteString(message.Content + "\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// Function to write the transcript to -transcript. When the session hit -max-turns without
+// -transcript, it is written to a timestamped file so nothing is lost at the limit.
+func (s *ChatSession) SaveTranscript(path string) {
+	if path == "" {
+		if s.maxTurns <= 0 || s.turn < s.maxTurns {
+			return
+		}
+		path = fmt.Sprintf("transcript-%s.md", time.Now().Format("20060102-150405"))
+	}
+	if s.turn == 0 {
+		return
+	}
+	if err := s.writeTranscript(path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// Helper function to write the transcript to a file, redacted and stripped like the other outputs
+func (s *ChatSession) writeTranscript(path string) error {
+	transcript := redactText(s.Transcript(), s.requestOptions.Redactions)
+	if shouldStripANSI(s.stripANSI, path) {
+		transcript = stripANSI(transcript)
+	}
+	if err := ioutil.WriteFile(path, []byte(transcript), 0644); err != nil {
+		return fmt.Errorf("Error writing to file %s: %v", path, err)
+	}
+	progressf("\nTranscript saved to %s\n", path)
+	return nil
+}
+
+// Function to answer a single general Kubernetes question from -ask or stdin, without a log or key points pass
+func runQuestion(ctx context.Context, opts *Options, requestOptions RequestOptions) error {
+	question := opts.Ask
+	if question == "" {
+		if isTerminal(os.Stdin) {
+			progressf("Enter your question (end with Ctrl-D):\n")
+		}
+		input, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("Error reading question from stdin: %v", err)
+		}
+		question = string(input)
+
+		// Stdin is used up by the question, so a confirmation could not be answered
+		requestOptions.Interactive = false
+	}
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return fmt.Errorf("Error: provide a question with -ask or on stdin, or a log with -log")
+	}
+
+	metadata, err := newRunMetadata(opts, requestOptions.Redactions)
+	if err != nil {
+		return err
+	}
+	defer metadata.Close()
+	defer printCostSummary(metadata)
+
+	prompt, err := renderSystemPrompt(requestOptions, "", nil)
+	if err != nil {
+		return err
+	}
+
+	session := &ChatSession{
+		messages:       newConversation(prompt),
+		requestOptions: withModel(requestOptions, opts.AnalysisModel),
+		metadata:       metadata,
+		echoPrompt:     opts.EchoPrompt,
+	}
+	questionCtx, stop := turnContext(ctx)
+	defer stop()
+	if err := session.Send(questionCtx, question); err != nil {
+		return err
+	}
+
+	// Optionally save the question and answer as Markdown
+	if opts.SaveAnswer != "" {
+		answer := session.messages[len(session.messages)-1].Content
+		content := fmt.Sprintf("# Question\n\n%s\n\n# Answer\n\n%s\n", quotePrompt(question), answer)
+		content = redactText(content, requestOptions.Redactions)
+		if shouldStripANSI(opts.StripANSI, opts.SaveAnswer) {
+			content = stripANSI(content)
+		}
+		if err := ioutil.WriteFile(opts.SaveAnswer, []byte(content), 0644); err != nil {
+			return fmt.Errorf("Error writing to file %s: %v", opts.SaveAnswer, err)
+		}
+		progressf("\nAnswer saved to %s\n", opts.SaveAnswer)
+	}
+	return nil
+}
+
+// Function to send a message of an interactive turn: Ctrl+C cancels the response, and the session goes on
+func (s *ChatSession) SendTurn(ctx context.Context, userInput string) error {
+	turnCtx, stop := turnContext(ctx)
+	defer stop()
+
+	err := s.Send(turnCtx, userInput)
+	if err != nil && turnCtx.Err() != nil && ctx.Err() == nil {
+		progressf("\nResponse cancelled.\n")
+		return nil
+	}
+	return err
+}
+
+// Function to send a user message and append the assistant's response to the conversation
+func (s *ChatSession) Send(ctx context.Context, userInput string) error {
+	return s.send(ctx, userInput, false)
+}
+
+// Helper function to send a user message and record the exchange. A retried exchange keeps
+// the turn number and replaces the last exchange of the transcript.
+func (s *ChatSession) send(ctx context.Context, userInput string, retry bool) error {
+	if s.echoPrompt {
+		progressf("\n%s\n", quotePrompt(userInput))
+	}
+
+	// Condense older turns before the history grows past -summarize-tokens
+	if err := s.summarizeHistory(ctx); err != nil {
+		return err
+	}
+
+	// Append user's message to a copy of the messages, trimmed to -max-history-tokens.
+	// The conversation only changes once the request succeeds, so a failed turn drops nothing.
+	messages := append(s.messages[:len(s.messages):len(s.messages)], Message{
+		Role:    "user",
+		Content: userInput,
+	})
+	messages, dropped := s.trimHistory(messages)
+
+	// Send request with updated messages
+	assistantResult, err := sendRequest(ctx, messages, s.requestOptions)
+	if err != nil {
+		return err
+	}
+	s.messages = messages
+	s.commitTrim(dropped)
+
+	if !retry {
+		s.turn++
+	}
+	passName := fmt.Sprintf("Interactive Turn %d", s.turn)
+	if retry {
+		passName += " (retry)"
+	}
+	s.metadata.Record(passName, s.requestOptions.Model, assistantResult)
+
+	// With -quiet the rendered response is suppressed, so the raw response is the only output
+	if quiet {
+		fmt.Println(assistantResult.Content)
+	}
+
+	// Append assistant's response to messages
+	s.messages = append(s.messages, Message{
+		Role:    "assistant",
+		Content: assistantResult.Content,
+	})
+	exchange := s.messages[len(s.messages)-2:]
+	if retry && len(s.history) >= 2 {
+		s.history = s.history[:len(s.history)-2]
+	}
+	s.history = append(s.history, exchange...)
+	if err := s.sessionLog.Append(s.turn, exchange[0], exchange[1], retry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Suggestions are pointless once the last allowed turn was answered
+	if s.suggest && (s.maxTurns <= 0 || s.turn < s.maxTurns) {
+		s.suggestFollowUps(ctx)
+	}
+	return nil
+}
+
+// Helper function to format a user question as a "> " quoted block
+func quotePrompt(userInput string) string {
+	lines := strings.Split(strings.TrimRight(userInput, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// checklistPrompt asks for the recommendations as a task list that can be copied into an issue tracker
+const checklistPrompt = "\n\nFormat the recommendations as a Markdown task list under a Recommendations heading: " +
+	"one actionable step per `- [ ] ` item, so the list can be copied into an issue tracker as is."
+
+var (
+	// A heading or bold label starting a recommendations section
+	checklistHeadingRegex = regexp.MustCompile(`(?i)^\s*(?:#{1,6}\s+|\*\*|__).*recommendation`)
+
+	// Any heading or bold-only label, which ends the recommendations section
+	checklistSectionEndRegex = regexp.MustCompile(`^\s*(?:#{1,6}\s+\S|(?:\*\*|__)[^*_]+(?:\*\*|__):?\s*$)`)
+
+	// A top-level bullet or numbered item, with an existing checkbox if any
+	checklistItemRegex = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[([ xX])\]\s+)?`)
+)
+
+// Function to turn every top-level item of the recommendations sections into a "- [ ] " checkbox,
+// for models that did not follow the -checklist instruction. Nested items and code blocks are left as is.
+func applyChecklist(analysis string) string {
+	lines := strings.Split(analysis, "\n")
+	inSection, inFence := false, false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		switch {
+		case checklistHeadingRegex.MatchString(line):
+			inSection = true
+		case checklistSectionEndRegex.MatchString(line):
+			inSection = false
+		case inSection:
+			// Keep the state of items the model already checked
+			if loc := checklistItemRegex.FindStringSubmatchIndex(line); loc != nil {
+				box := "- [ ] "
+				if loc[2] >= 0 && line[loc[2]:loc[3]] != " " {
+					box = "- [x] "
+				}
+				lines[i] = box + line[loc[1]:]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,876 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Options holds the values of all command-line flags.
+// Each subcommand only registers the flag groups relevant to it.
+type Options struct {
+	// Log selection
+	LogPattern       string
+	SplitContainers  bool
+	ContextDelimiter string
+	RestartMarker    string
+	SinceRestart     bool
+	AccessLogPattern string
+	CRIFormat        bool
+
+	// Prefix the log lines sent to the model with their line numbers
+	IncludeLineNumbers bool
+
+	// Related logs included as additional context
+	AlsoLogs      stringListFlag
+	AlsoLogTokens int
+
+	// Request behavior
+	ConfigFile   string
+	Endpoint     string
+	AuthScheme   string
+	Model        string
+	Stream       bool
+	DelayMs      int
+	NoTypewriter bool
+	Resume       bool
+	Benchmark    bool
+	RenderMs     int
+	StrictModel  bool
+	FailOnPII    bool
+	RetainRaw    string
+	PricingFile  string
+	ShowUsage    bool
+	RedactConfig string
+	StripANSI    string
+	Theme        string
+	Width        int
+	Raw          bool
+	StreamRender bool
+	FixMarkdown  bool
+	RetriesLog   string
+	Retry        RetryPolicy
+	TimeoutSec   int
+	RecordDir    string
+	ReplayDir    string
+
+	// Flat per-1K token prices for models missing from the -pricing file
+	PricePerKPrompt     float64
+	PricePerKCompletion float64
+
+	// Extra request headers from the -config file, and from -header which may override any header
+	Headers      map[string]string
+	HeaderValues stringListFlag
+
+	// Per-pass model overrides of -model
+	KeyPointsModel string
+	AnalysisModel  string
+
+	// System prompt of the analysis and interactive passes
+	SystemPromptFile string
+	NoSystemPrompt   bool
+	PromptVars       stringListFlag
+
+	// Large prompt safeguard. Interactive is set from the command rather than a flag:
+	// only interactive runs ask for confirmation, the others abort.
+	PromptTokensWarn int
+	AssumeYes        bool
+	Interactive      bool
+
+	// Share of the context window used by the log input, leaving the rest for the response
+	ContextWindow  int
+	ContextPercent int
+
+	// Confirm before sending requests to an endpoint matching ProductionPattern
+	ConfirmEndpoint   bool
+	ProductionPattern string
+
+	// Interactive session
+	Ask         string
+	EchoPrompt  bool
+	SaveAnswer  string
+	MaxTurns    int
+	Transcript  string
+	SaveSession string
+	LoadSession string
+
+	// Summarize the oldest half of the conversation once it exceeds SummarizeTokens
+	AutoSummarize   bool
+	SummarizeTokens int
+
+	// Drop the oldest messages once the conversation exceeds MaxHistoryTokens
+	MaxHistoryTokens int
+
+	// Propose numbered follow-up questions after each response
+	Suggest bool
+
+	// Lines arriving within this many milliseconds of each other are sent as one message
+	PasteDebounceMs int
+
+	// Loki query generation
+	Loki LokiOptions
+
+	// Non-interactive analysis output
+	SinceFile          string
+	OutputFile         string
+	Formats            string
+	Format             string
+	OutputEncoding     string
+	OutputDir          string
+	OutputNameTemplate string
+	GroupBy            string
+	DedupeAcrossFiles  bool
+	MergeOutput        string
+	MaxFiles           int
+	TraceURL           string
+	ValidateOutput     bool
+	AnalyzeJSON        bool
+	DeepDive           bool
+	DetectLanguage     bool
+	ClassifyOnly       bool
+	Checklist          bool
+	OutputDiff         string
+	TokenBreakdown     bool
+	FailOnSeverity     string
+	OutputOnError      bool
+	Webhook            string
+	WebhookHeaders     stringListFlag
+}
+
+// Command represents a subcommand with its own flag set
+type Command struct {
+	Name        string
+	Description string
+	Flags       func(fs *flag.FlagSet, opts *Options)
+	Run         func(ctx context.Context, opts *Options) error
+
+	// Interactive commands handle Ctrl+C themselves, cancelling only the current response
+	Interactive bool
+}
+
+// commands lists the available subcommands in the order they are shown in the usage
+var commands = []Command{
+	{
+		Name:        "analyze",
+		Description: "Generate key points and a full analysis for a log, then export it as Markdown",
+		Flags: func(fs *flag.FlagSet, opts *Options) {
+			addLogFlags(fs, opts)
+			addRequestFlags(fs, opts)
+			addAnalysisFlags(fs, opts)
+			addLokiFlags(fs, opts)
+			addLokiContextFlags(fs, opts)
+		},
+		Run: runAnalyze,
+	},
+	{
+		Name:        "chat",
+		Description: "Generate key points for a log, then start an interactive troubleshooting session (without -log, answer one question)",
+		Flags: func(fs *flag.FlagSet, opts *Options) {
+			addLogFlags(fs, opts)
+			addRequestFlags(fs, opts)
+			addChatFlags(fs, opts)
+		},
+		Run:         runChat,
+		Interactive: true,
+	},
+	{
+		Name:        "loki",
+		Description: "Generate Loki query commands for a log without calling the model",
+		Flags: func(fs *flag.FlagSet, opts *Options) {
+			addLogFlags(fs, opts)
+			addLokiFlags(fs, opts)
+		},
+		Run: runLoki,
+	},
+	{
+		Name:        "selftest",
+		Description: "Send a minimal request to verify the API keys and endpoint",
+		Flags: func(fs *flag.FlagSet, opts *Options) {
+			addRequestFlags(fs, opts)
+		},
+		Run: runSelftest,
+	},
+}
+
+// Function to register the log selection flags
+func addLogFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.LogPattern, "log", "", "Partial log filename to match in the LOGS/ directory (e.g., '01-LOG'); the first match is processed")
+	fs.StringVar(&opts.ContextDelimiter, "context-delimiter", "xml", "How the log is framed in the key points request: xml (<context>), backticks, markers (### LOG START ###), or tag:<name>")
+	fs.Var(&opts.AlsoLogs, "also-log", "Partial filename of a related log to include as additional context (repeatable)")
+	fs.IntVar(&opts.AlsoLogTokens, "also-log-tokens", 8000, "Token budget shared by the -also-log logs; the oldest lines are dropped beyond it")
+	fs.StringVar(&opts.AccessLogPattern, "access-log-pattern", "", "Regular expression matching access log lines, capturing the HTTP status code in its first group or a group named 'status' (default: common access log formats)")
+	fs.BoolVar(&opts.SinceRestart, "since-restart", false, "Only analyze the content after the last detected restart (see -restart-marker), falling back to the full log")
+	fs.StringVar(&opts.RestartMarker, "restart-marker", "", "Regular expression matching the startup banner of the container, used to count restarts (default: common server startup messages)")
+	fs.BoolVar(&opts.CRIFormat, "cri-format", false, "Parse the container runtime (CRI) format of the logs under /var/log/pods ('<timestamp> <stream> <P|F> <message>'), joining partial lines and using their timestamps")
+	fs.BoolVar(&opts.IncludeLineNumbers, "include-line-numbers", false, "Prefix each log line sent to the model with its line number and ask for line citations, so cited events can be found in the file")
+	fs.BoolVar(&opts.SplitContainers, "split-containers", false, "Split interleaved '[pod/<pod>/<container>]' prefixed logs and generate key points per container")
+}
+
+// Function to register the flags controlling how requests are sent and displayed
+func addRequestFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.ConfigFile, "config", "", "JSON config file setting the endpoint, model, headers, delay, output file and prompts; flags given on the command line take precedence")
+	fs.StringVar(&opts.AuthScheme, "auth-scheme", "raw", "How K8s_APIKEY is sent in the Authorization header: raw (the key as is) or bearer ('Bearer <key>')")
+	fs.Var(&opts.HeaderValues, "header", "Extra request header 'Name: value', e.g. an organization or X-Request-ID header; overrides the config file and built-in headers (repeatable)")
+	fs.StringVar(&opts.Endpoint, "endpoint", "", "Chat completions URL of the API gateway (default $K8S_API_URL)")
+	fs.StringVar(&opts.Model, "model", "", "Model to request (default $K8S_MODEL, or "+defaultModel+")")
+	fs.StringVar(&opts.KeyPointsModel, "keypoints-model", "", "Model for the key points pass, overriding -model")
+	fs.StringVar(&opts.AnalysisModel, "analysis-model", "", "Model for the analysis and follow-up passes (chat turns, deep dives), overriding -model")
+	fs.BoolVar(&opts.Stream, "stream", false, "Enable streaming output")
+	fs.IntVar(&opts.DelayMs, "delay", 10, "Delay in milliseconds between streaming chunks")
+	fs.StringVar(&opts.SystemPromptFile, "system-prompt-file", "", "Replace the built-in Kubernetes expert system prompt with the contents of this file")
+	fs.Var(&opts.PromptVars, "prompt-var", "Variable key=value for the system prompt template, e.g. cluster=prod-eu (repeatable)")
+	fs.BoolVar(&opts.NoSystemPrompt, "no-system-prompt", false, "Omit the system message entirely, to compare the model's raw behavior (cannot be combined with -system-prompt-file)")
+	fs.IntVar(&opts.ContextWindow, "context-window", 128000, "Context window of the model in tokens, used by -context-percent")
+	fs.IntVar(&opts.ContextPercent, "context-percent", 0, "Percent of -context-window the key points input may use, truncating the oldest log lines to leave room for the response (0 disables)")
+	fs.BoolVar(&opts.Resume, "resume", false, "When a stream is interrupted, re-prompt the model to continue from the end of the partial response and stitch the parts together")
+	fs.BoolVar(&opts.Benchmark, "benchmark", false, "Measure time to first token, tokens per second and total latency of each streamed request, with averages at the end of the run (requires -stream, disables the typewriter delay)")
+	fs.StringVar(&opts.Theme, "theme", "auto", "Style of the rendered Markdown: auto (dark on a terminal, notty when piped), dark, light, notty or dracula")
+	fs.BoolVar(&opts.Raw, "raw", false, "Print the plain Markdown of the responses without glamour rendering, e.g. when piping into another tool; saved files are unaffected")
+	fs.BoolVar(&opts.StreamRender, "stream-render", true, "With -stream, print the rendered response under 'Formatted Response' once the stream completes; false shows the streamed text only")
+	fs.IntVar(&opts.Width, "width", 0, "Word-wrap width of the rendered Markdown (0 uses the terminal width, or 80 when not a terminal)")
+	fs.IntVar(&opts.RenderMs, "render-interval", 0, "Re-render the streamed Markdown every N milliseconds on a terminal, showing raw deltas in between (0 renders only at the end)")
+	fs.BoolVar(&opts.NoTypewriter, "no-typewriter", false, "Stream output without the per-chunk delay")
+	fs.BoolVar(&opts.StrictModel, "strict-model", false, "Fail when the server responds with a different model than requested")
+	fs.BoolVar(&opts.FailOnPII, "fail-on-pii", false, "Exit with code 4 when the gateway's guardrails report PII in a response or a redacted response")
+	fs.IntVar(&opts.PromptTokensWarn, "prompt-tokens-warn", 30000, "Ask before sending a prompt estimated above this many tokens (chat asks, analyze and a question read from stdin abort); 0 disables")
+	fs.BoolVar(&opts.ConfirmEndpoint, "confirm-endpoint", false, "Ask for confirmation (or require -yes when not a terminal) before sending requests to an endpoint matching -production-pattern")
+	fs.StringVar(&opts.ProductionPattern, "production-pattern", `(?i)prod`, "Regular expression matching production endpoints for -confirm-endpoint")
+	fs.BoolVar(&opts.AssumeYes, "yes", false, "Assume yes for confirmations such as -prompt-tokens-warn -max-files and -confirm-endpoint")
+	fs.StringVar(&opts.StripANSI, "strip-ansi", "auto", "Remove residual ANSI escape sequences from the written output: auto (files and piped stdout, not a terminal), always or never")
+	fs.StringVar(&opts.RedactConfig, "redact-config", "", "JSON file of custom regex patterns and labels, e.g. {\"patterns\": [{\"pattern\": \"ACCT-[0-9]{8}\", \"label\": \"ACCOUNT_ID\"}]}, redacted from every request and written output")
+	fs.StringVar(&opts.PricingFile, "pricing", "", "JSON file mapping model names to per-1K token prices, e.g. {\"gpt-4o\": {\"prompt\": 0.0025, \"completion\": 0.01}}")
+	fs.Float64Var(&opts.PricePerKPrompt, "price-per-1k-prompt", 0, "Price in dollars per 1K prompt tokens for models without a -pricing entry, e.g. 0.0025")
+	fs.Float64Var(&opts.PricePerKCompletion, "price-per-1k-completion", 0, "Price in dollars per 1K completion tokens for models without a -pricing entry, e.g. 0.01")
+	fs.BoolVar(&opts.ShowUsage, "show-usage", false, "Print a table of the token usage (and estimated cost, when priced) after each request, and the run total at the end")
+	fs.BoolVar(&opts.FixMarkdown, "fix-markdown", false, "Repair common Markdown issues (unclosed code fences, malformed tables) in responses before rendering and saving")
+	fs.IntVar(&opts.TimeoutSec, "timeout", 0, "Seconds before a request attempt is aborted, including the whole stream with -stream (0 for no timeout)")
+	fs.IntVar(&opts.Retry.TimeoutRetries, "timeout-retries", 0, "Number of times a timed out request is retried")
+	fs.IntVar(&opts.Retry.ErrorRetries, "error-retries", 3, "Number of times a request failing with HTTP 429, 500, 502, 503 or 504 is retried")
+	fs.IntVar(&opts.Retry.MaxRetries, "max-retries", 3, "Total number of retries of a request across every kind of failure, with exponential backoff and jitter (0 disables retries)")
+	fs.IntVar(&opts.Retry.ConnectionRetries, "connection-retries", 3, "Number of times a request failing with a connection error (e.g. refused or reset) is retried")
+	fs.StringVar(&opts.RetriesLog, "retries-log", "", "Append a JSON line per request attempt (request ID, status code, delay, outcome) to this file")
+	fs.StringVar(&opts.RecordDir, "record", "", "Record every API response to this directory for later -replay")
+	fs.StringVar(&opts.ReplayDir, "replay", "", "Replay API responses recorded with -record from this directory instead of calling the API")
+	fs.StringVar(&opts.RetainRaw, "retain-raw", "", "Save the raw, unrendered Markdown of every assistant response to this file")
+	fs.BoolVar(&verbose, "v", false, "Enable verbose diagnostic output on stderr")
+	fs.BoolVar(&quiet, "quiet", false, "Suppress banners, rendered responses and progress messages; only the final result is written to stdout")
+}
+
+// Function to register the flags for the non-interactive analysis output
+func addAnalysisFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.OutputFile, "output", "output.md", "Output Markdown file")
+	fs.StringVar(&opts.OutputEncoding, "output-encoding", "utf-8", "Character encoding of the written output: utf-8, utf-16 or latin1")
+	fs.StringVar(&opts.Formats, "formats", defaultFormats, "Comma-separated output formats (markdown, json, html, sarif); other formats are written next to -output with their own extension")
+	fs.StringVar(&opts.Format, "format", "", "Single output format (markdown, json, html or sarif), written to -output with the format's extension unless -output has its own, e.g. -format html for a styled, self-contained page")
+	fs.StringVar(&opts.OutputDir, "output-dir", "", "Analyze every matching log, writing each to its own file in this directory")
+	fs.StringVar(&opts.OutputNameTemplate, "output-name-template", "{{.Base}}-analysis{{.Ext}}", "File name template for -output-dir (fields: .Name, .Base, .Ext, .Index)")
+	fs.IntVar(&opts.MaxFiles, "max-files", 50, "With -output-dir, ask for confirmation (or require -yes when not a terminal) before analyzing more than N logs (0 disables the check)")
+	fs.BoolVar(&opts.DedupeAcrossFiles, "dedupe-across-files", false, "In the -group-by summary, collapse logs with near-identical key points into one entry with a count (implies -group-by=file if unset)")
+	fs.StringVar(&opts.MergeOutput, "merge-output", "", "With -output-dir, also combine every analysis into this Markdown file, with a table of contents sorted by severity")
+	fs.StringVar(&opts.GroupBy, "group-by", "", "With -output-dir, also write a summary.md grouping the logs by namespace, pod or file")
+	fs.StringVar(&opts.SinceFile, "since-file", "", "State file tracking the last processed offset per log, so only newly appended content is analyzed")
+	fs.StringVar(&opts.TraceURL, "trace-url", "", "Base URL for trace links (e.g., Jaeger or Grafana with a {trace_id} placeholder)")
+	fs.BoolVar(&opts.AnalyzeJSON, "analyze-json", false, "Request the analysis as JSON validated against the embedded schema (summary, severity, rootCauses, recommendations, affectedResources), re-prompting once if invalid")
+	fs.StringVar(&opts.Webhook, "webhook", "", "POST each completed analysis as JSON to this URL, e.g. to feed a ticketing or chat system")
+	fs.Var(&opts.WebhookHeaders, "webhook-header", "Header 'Name: value' sent with -webhook, e.g. for authentication (repeatable)")
+	fs.BoolVar(&opts.DeepDive, "deep-dive", false, "After the analysis, request a remediation runbook for each locally detected issue and append them to the output")
+	fs.BoolVar(&opts.OutputOnError, "output-on-error", false, "When a step fails, still write the partial results and the error to the output files, marked as a failed run")
+	fs.StringVar(&opts.FailOnSeverity, "fail-on-severity", "", "Exit with code 3 when an analysis severity meets or exceeds this threshold (critical, high, medium, low, info, or the finding levels error, warning, note)")
+	fs.BoolVar(&opts.TokenBreakdown, "token-breakdown", false, "Estimate the tokens of each prompt component (system prompt, key points instructions, log content, ...) and report them under -v and in the metadata")
+	fs.StringVar(&opts.OutputDiff, "output-diff", "", "Previous analysis output (Markdown or JSON) to compare with, appending what changed in issues, severity and recommendations")
+	fs.BoolVar(&opts.Checklist, "checklist", false, "Format the recommendations as a Markdown task list ('- [ ] ...') that can be copied into an issue tracker")
+	fs.BoolVar(&opts.ClassifyOnly, "classify-only", false, "Run only the local detection (findings, timeline, trace IDs, Loki queries) and write the report without any model call or API keys")
+	fs.BoolVar(&opts.DetectLanguage, "detect-language", false, "Detect the dominant language of the log messages, record it in the metadata and ask for English translations of quoted lines when it is not English")
+	fs.BoolVar(&opts.ValidateOutput, "validate-output", false, "Check the analysis for a markdown table and recommendations list, re-prompting once if missing")
+}
+
+// Function to register the flags for the interactive session
+func addChatFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.Ask, "ask", "", "Seed the session with this first question, then continue interactively")
+	fs.IntVar(&opts.PasteDebounceMs, "paste-debounce", 30, "Batch lines arriving within this many milliseconds of each other (a paste) into one message; 0 disables")
+	fs.StringVar(&opts.SaveAnswer, "save-answer", "", "Without -log: save the question and answer to this Markdown file")
+	fs.IntVar(&opts.MaxTurns, "max-turns", 0, "End the interactive session after N exchanges, warning as the limit approaches (0 for no limit)")
+	fs.StringVar(&opts.SaveSession, "save-session", "", "Append each exchange of the interactive session with timestamps to this file as it proceeds: JSONL for a .jsonl file (loadable again), Markdown otherwise")
+	fs.StringVar(&opts.LoadSession, "load-session", "", "Continue a session saved with -save-session to a .jsonl file, loading its full conversation before interactive mode (cannot be combined with -log)")
+	fs.StringVar(&opts.Transcript, "transcript", "", "Save the interactive session as Markdown to this file when it ends (written to transcript-<time>.md at the -max-turns limit if unset)")
+	fs.BoolVar(&opts.AutoSummarize, "auto-summarize", false, "Condense the oldest half of the interactive conversation into a summary whenever it exceeds -summarize-tokens")
+	fs.IntVar(&opts.SummarizeTokens, "summarize-tokens", 16000, "Estimated history size in tokens that triggers -auto-summarize")
+	fs.IntVar(&opts.MaxHistoryTokens, "max-history-tokens", 0, "Drop the oldest messages of the interactive conversation once it exceeds this many estimated tokens, always keeping the system prompt (0 disables)")
+	fs.BoolVar(&opts.Suggest, "suggest", false, "After each response, propose 3 follow-up questions as numbered options; type a number to ask one (one extra small request per turn)")
+	fs.BoolVar(&opts.EchoPrompt, "echo-prompt", false, "Re-print each question with a '> ' marker before its response, for self-contained transcripts")
+}
+
+// Function to register the flags for Loki query generation
+func addLokiFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.Loki.URL, "loki-url", lokiDefaultURL, "Loki query_range endpoint used by the generated queries")
+	fs.IntVar(&opts.Loki.Limit, "loki-limit", 1000, fmt.Sprintf("Maximum number of lines returned by the generated Loki queries (1-%d)", lokiMaxLimit))
+	fs.BoolVar(&opts.Loki.Run, "run-loki", false, "Execute the generated Loki queries and include their results (uses LOKI_TOKEN for auth)")
+	fs.BoolVar(&opts.Loki.Run, "loki-fetch", false, "Alias of -run-loki: fetch the lines matched by the generated Loki queries into a 'Correlated Loki Logs' section")
+	fs.IntVar(&opts.Loki.Concurrency, "loki-concurrency", 2, "Maximum number of Loki queries executed at the same time")
+	fs.StringVar(&opts.Loki.Direction, "loki-direction", "backward", "Order of the lines returned by Loki: 'backward' (newest first) or 'forward'")
+	fs.BoolVar(&opts.Loki.PrettyCurl, "prettify-curl", false, "Format the generated curl commands over several lines, one --data-urlencode per parameter")
+}
+
+// Function to register the flags for enriching the analysis with context fetched from Loki
+func addLokiContextFlags(fs *flag.FlagSet, opts *Options) {
+	fs.BoolVar(&opts.Loki.Prepend, "loki-prepend", false, "Run the generated Loki query and prepend the surrounding context to the analysis input (uses LOKI_TOKEN for auth)")
+	fs.IntVar(&opts.Loki.ContextTokens, "loki-context-tokens", 8000, "Token budget for the prepended Loki context; the oldest lines are dropped beyond it")
+}
+
+// Function to print the top-level usage listing the subcommands
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.Name, command.Description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Example: %s analyze -log=\"01-LOG\" -output=\"analysis.md\"\n", os.Args[0])
+}
+
+// Function to parse the flags of a subcommand and run it
+func runCommand(command Command, args []string) error {
+	var opts Options
+	fs := flag.NewFlagSet(command.Name, flag.ExitOnError)
+	command.Flags(fs, &opts)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags]\n\n%s.\n\nFlags:\n", os.Args[0], command.Name, command.Description)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyConfigFile(fs, &opts); err != nil {
+		return err
+	}
+	configureOutput(&opts)
+	opts.Interactive = command.Interactive
+
+	ctx, stop := interruptContext(command.Interactive)
+	defer stop()
+	return command.Run(ctx, &opts)
+}
+
+// Function to decide where progress output goes once the flags are parsed.
+// -quiet discards it, and -output=- moves it to stderr so stdout only carries the analysis.
+func configureOutput(opts *Options) {
+	switch {
+	case quiet:
+		progressOut = ioutil.Discard
+	case opts.OutputFile == "-" && opts.OutputDir == "":
+		progressOut = os.Stderr
+	}
+}
+
+// Function to run the deprecated flat flag set, which predates the subcommands.
+// -noninteractive maps to the analyze command, otherwise the chat command is run.
+func runLegacy(args []string) error {
+	var opts Options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	addLogFlags(fs, &opts)
+	addRequestFlags(fs, &opts)
+	addAnalysisFlags(fs, &opts)
+	addLokiFlags(fs, &opts)
+	addLokiContextFlags(fs, &opts)
+	addChatFlags(fs, &opts)
+	nonInteractive := fs.Bool("noninteractive", false, "Enable non-interactive mode (deprecated: use the analyze command)")
+	fs.Usage = func() {
+		printUsage()
+		fmt.Fprintf(os.Stderr, "\nDeprecated flat flags (still accepted):\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyConfigFile(fs, &opts); err != nil {
+		return err
+	}
+	configureOutput(&opts)
+	opts.Interactive = !*nonInteractive
+
+	ctx, stop := interruptContext(!*nonInteractive)
+	defer stop()
+	if *nonInteractive {
+		fmt.Fprintf(os.Stderr, "Warning: flat flags are deprecated, use '%s analyze' instead.\n", os.Args[0])
+		return runAnalyze(ctx, &opts)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: flat flags are deprecated, use '%s chat' instead.\n", os.Args[0])
+	return runChat(ctx, &opts)
+}
+
+// defaultModel is requested when neither -model nor K8S_MODEL is set
+const defaultModel = "gpt-4o"
+
+// Function to build the request options from the environment and flags
+func newRequestOptions(opts *Options) (RequestOptions, error) {
+	if opts.RecordDir != "" && opts.ReplayDir != "" {
+		return RequestOptions{}, fmt.Errorf("Error: -record and -replay cannot be used together")
+	}
+	if opts.IncludeLineNumbers && opts.CRIFormat {
+		return RequestOptions{}, fmt.Errorf("Error: -include-line-numbers cannot be used with -cri-format, as joined partial lines no longer match the lines of the file")
+	}
+
+	// Retrieve API keys from environment variables
+	APIKey := os.Getenv("K8s_APIKEY")
+	openAIKey := os.Getenv("OPENAI_API_KEY")
+
+	// Replays and -classify-only run offline, so the keys are only required when calling the API
+	offline := opts.ReplayDir != "" || opts.ClassifyOnly
+	if APIKey == "" && !offline {
+		return RequestOptions{}, fmt.Errorf("Error: K8s_APIKEY environment variable is not set.")
+	}
+
+	if openAIKey == "" && !offline {
+		return RequestOptions{}, fmt.Errorf("Error: OPENAI_API_KEY environment variable is not set.")
+	}
+
+	// Resolve the API endpoint from -endpoint or K8S_API_URL
+	endpoint, err := resolveEndpoint(opts.Endpoint, offline)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+
+	// Guard against accidentally running against the production gateway; offline runs never call it
+	if opts.ConfirmEndpoint && !offline {
+		if err := confirmEndpoint(endpoint, opts); err != nil {
+			return RequestOptions{}, err
+		}
+	}
+
+	authorization, err := authorizationHeader(APIKey, opts.AuthScheme)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+
+	// Create the request headers
+	headers := map[string]string{
+		"Content-Type":   "application/json",
+		"Authorization":  authorization,
+		"OpenAI-Api-Key": openAIKey,
+	}
+	mergeHeaders(headers, opts.Headers)
+
+	// Command-line headers are explicit, so they may replace the built-in ones (e.g. for custom auth)
+	flagHeaders, err := parseHeaders("header", opts.HeaderValues)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+	for name, value := range flagHeaders {
+		setHeader(headers, name, value)
+	}
+
+	// Compute the delay duration
+	delay := time.Duration(opts.DelayMs) * time.Millisecond
+
+	// Disable the typewriter effect for fast capture of large streamed responses.
+	// Benchmarks measure the stream itself, so the delay would skew them.
+	if opts.NoTypewriter || opts.Benchmark {
+		delay = 0
+	}
+	if opts.Benchmark && !opts.Stream {
+		return RequestOptions{}, fmt.Errorf("Error: -benchmark measures streamed responses and requires -stream")
+	}
+
+	prompt, err := loadSystemPrompt(opts)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+	promptVariables, err := parsePromptVariables(opts.PromptVars)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+
+	redactions, err := loadRedactions(opts.RedactConfig)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+	if err := parseStripANSI(opts.StripANSI); err != nil {
+		return RequestOptions{}, err
+	}
+	theme, err := parseTheme(opts.Theme)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+	width, err := resolveWidth(opts.Width)
+	if err != nil {
+		return RequestOptions{}, err
+	}
+
+	if opts.ContextPercent < 0 || opts.ContextPercent > 100 {
+		return RequestOptions{}, fmt.Errorf("Error: -context-percent must be between 0 and 100, got %d", opts.ContextPercent)
+	}
+
+	// Continue interrupted streams a bounded number of times
+	resumes := 0
+	if opts.Resume {
+		resumes = resumeMaxAttempts
+	}
+
+	requestOptions := RequestOptions{
+		URL:            endpoint,
+		Headers:        headers,
+		Model:          resolveModel(opts.Model),
+		Stream:         opts.Stream,
+		Delay:          delay,
+		RenderInterval: time.Duration(opts.RenderMs) * time.Millisecond,
+		Theme:          theme,
+		Width:          width,
+		Raw:            opts.Raw,
+		StreamRender:   opts.StreamRender,
+		StrictModel:    opts.StrictModel,
+		FailOnPII:      opts.FailOnPII,
+		Resumes:        resumes,
+		Benchmark:      opts.Benchmark,
+		InputTokens:    opts.ContextWindow * opts.ContextPercent / 100,
+		SystemPrompt:   prompt,
+
+		PromptTokensWarn: opts.PromptTokensWarn,
+		AssumeYes:        opts.AssumeYes,
+		Interactive:      opts.Interactive,
+		FixMarkdown:      opts.FixMarkdown,
+		RetriesLog:       opts.RetriesLog,
+		RecordDir:        opts.RecordDir,
+		ReplayDir:        opts.ReplayDir,
+		Retry:            opts.Retry,
+		Timeout:          time.Duration(opts.TimeoutSec) * time.Second,
+		PromptVariables:  promptVariables,
+		Redactions:       redactions,
+	}
+
+	// Catch undefined variables and template errors before any request is sent
+	if _, err := renderSystemPrompt(requestOptions, "", nil); err != nil {
+		return RequestOptions{}, err
+	}
+	return requestOptions, nil
+}
+
+// Function to resolve the requested model: the -model flag takes precedence over the K8S_MODEL
+// environment variable, falling back to the default model
+func resolveModel(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if model := os.Getenv("K8S_MODEL"); model != "" {
+		return model
+	}
+	return defaultModel
+}
+
+// Helper function to derive the request options of a pass, using its model override when set
+func withModel(requestOptions RequestOptions, model string) RequestOptions {
+	if model != "" {
+		requestOptions.Model = model
+	}
+	return requestOptions
+}
+
+// Function to build the Authorization header from the API key and -auth-scheme: raw sends the key
+// as is, bearer prefixes it with "Bearer " unless the key already carries the prefix
+func authorizationHeader(key, scheme string) (string, error) {
+	switch strings.ToLower(scheme) {
+	case "raw":
+		return key, nil
+	case "bearer":
+		if key == "" || strings.HasPrefix(strings.ToLower(key), "bearer ") {
+			return key, nil
+		}
+		return "Bearer " + key, nil
+	default:
+		return "", fmt.Errorf("Error: invalid -auth-scheme %q: use raw or bearer", scheme)
+	}
+}
+
+// Function to resolve the chat completions endpoint: the -endpoint flag takes precedence over
+// the K8S_API_URL environment variable. Offline runs never call it, so it is only required otherwise.
+func resolveEndpoint(flagValue string, offline bool) (string, error) {
+	endpoint := flagValue
+	if endpoint == "" {
+		endpoint = os.Getenv("K8S_API_URL")
+	}
+	if endpoint == "" {
+		if offline {
+			return "", nil
+		}
+		return "", fmt.Errorf("Error: no API endpoint configured; set -endpoint or the K8S_API_URL environment variable")
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("Error: invalid API endpoint %q: %v", endpoint, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("Error: invalid API endpoint %q: expected an http or https URL such as https://gateway.example.com/v1/chat/completions", endpoint)
+	}
+	return endpoint, nil
+}
+
+// Function to ask for confirmation before using an endpoint matching the production pattern,
+// requiring -yes when stdin is not a terminal
+func confirmEndpoint(endpoint string, opts *Options) error {
+	production, err := regexp.Compile(opts.ProductionPattern)
+	if err != nil {
+		return fmt.Errorf("Error parsing -production-pattern: %v", err)
+	}
+	if !production.MatchString(endpoint) || opts.AssumeYes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("Error: endpoint %s matches the production pattern %q; pass -yes to use it anyway", endpoint, opts.ProductionPattern)
+	}
+	if !confirm(fmt.Sprintf("Endpoint %s looks like production, continue? [y/N] ", endpoint)) {
+		return fmt.Errorf("Request cancelled: production endpoint %s was not confirmed", endpoint)
+	}
+	return nil
+}
+
+// LogFile represents a log file selected for processing
+type LogFile struct {
+	Path    string
+	Content string
+
+	// Time window of the full content, preserved when the content is later split
+	Window TimeWindow
+
+	// Position to record in the -since-file after a successful run
+	since *SinceMarker
+
+	// Number of lines of the file before the content, skipped by -since-file or -since-restart
+	LineOffset int
+}
+
+// Function to find the log files matching the partial filename
+func findLogFiles(logPattern string) ([]string, error) {
+	// Check if log pattern is provided
+	if logPattern == "" {
+		return nil, fmt.Errorf("Please provide a partial log filename using the -log flag.")
+	}
+
+	// Define the log directory
+	logDir := "LOGS/"
+
+	// Create the pattern by appending '*' to the partial filename
+	pattern := logPattern + "*"
+
+	// Prepend the log directory to the pattern
+	pattern = logDir + pattern
+
+	// Use filepath.Glob to find matching files
+	fileList, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding files with pattern %s: %v", pattern, err)
+	}
+
+	// Check if any files were found
+	if len(fileList) == 0 {
+		return nil, fmt.Errorf("No files found matching pattern: %s", pattern)
+	}
+
+	return fileList, nil
+}
+
+// Function to read a log file, skipping content already processed according to the since file
+func readLog(path, sinceFile string) (LogFile, error) {
+	progressf("Processing file: %s\n", path)
+
+	// Read the contents of the selected file
+	logContent, err := ioutil.ReadFile(path)
+	if err != nil {
+		return LogFile{}, fmt.Errorf("Error reading %s: %v", path, err)
+	}
+
+	// Only keep the content appended since the last successful run
+	var marker *SinceMarker
+	lineOffset := 0
+	if sinceFile != "" {
+		fullContent := logContent
+		logContent, marker, err = applySinceFile(sinceFile, path, logContent)
+		if err != nil {
+			return LogFile{}, err
+		}
+		lineOffset = bytes.Count(fullContent[:len(fullContent)-len(logContent)], []byte("\n"))
+	}
+
+	// Convert log content to string
+	logString := string(logContent)
+
+	// Replace all double quotes with single quotes
+	logString = strings.ReplaceAll(logString, "\"", "'")
+
+	return LogFile{Path: path, Content: logString, Window: logTimeWindow(logString), since: marker, LineOffset: lineOffset}, nil
+}
+
+// Function to find and read the first log file matching the partial filename
+func loadLog(opts *Options) (LogFile, error) {
+	fileList, err := findLogFiles(opts.LogPattern)
+	if err != nil {
+		return LogFile{}, err
+	}
+
+	// Select the first matching file
+	logFile, err := readLog(fileList[0], opts.SinceFile)
+	if err != nil {
+		return LogFile{}, err
+	}
+	return applySinceRestart(opts, applyCRIFormat(opts, logFile))
+}
+
+// Function to trim the log to its latest run with -since-restart
+func applySinceRestart(opts *Options, logFile LogFile) (LogFile, error) {
+	if !opts.SinceRestart {
+		return logFile, nil
+	}
+	markers, err := restartMarkers(opts.RestartMarker)
+	if err != nil {
+		return LogFile{}, err
+	}
+	return trimToLatestRun(logFile, markers), nil
+}
+
+// Helper function to compute how many tokens of log content fit the input share of the context window
+// next to the key points prompt. It returns 0 when -context-percent is not set.
+func keyPointsBudget(passName string, delimiter ContextDelimiter, requestOptions RequestOptions) (int, error) {
+	if requestOptions.InputTokens <= 0 {
+		return 0, nil
+	}
+	budget := requestOptions.InputTokens - estimateMessagesTokens([]Message{{Content: delimiter.Frame(keyPointsPrompt, "")}})
+	if budget <= 0 {
+		return 0, fmt.Errorf("Error: -context-percent leaves no room for the log after the %s prompt", passName)
+	}
+	return budget, nil
+}
+
+// Function to send the first request, generating key points from the log content
+func generateKeyPoints(ctx context.Context, passName, logString string, delimiter ContextDelimiter, requestOptions RequestOptions, metadata *RunMetadata) (string, error) {
+	// Keep the most recent lines that fit the input share of the context window
+	budget, err := keyPointsBudget(passName, delimiter, requestOptions)
+	if err != nil {
+		return "", err
+	}
+	if budget > 0 && estimateTokens(logString) > budget {
+		lines, dropped := truncateToTokenBudget(strings.Split(logString, "\n"), budget)
+		fmt.Fprintf(os.Stderr, "Warning: %s input truncated to fit -context-percent, dropped the %d oldest lines\n", passName, dropped)
+		logString = strings.Join(lines, "\n")
+	}
+
+	// Combine the key points prompt with the log content
+	userContentFirst := delimiter.Frame(keyPointsPrompt, logString)
+
+	// First request messages (no system prompt)
+	messagesFirst := []Message{
+		{
+			Role:    "user",
+			Content: userContentFirst,
+		},
+	}
+
+	// Send the first request
+	firstResult, err := sendRequest(ctx, messagesFirst, requestOptions)
+	if err != nil {
+		return "", err
+	}
+	metadata.Record(passName, requestOptions.Model, firstResult)
+
+	return firstResult.Content, nil
+}
+
+// Function to generate the key points for a log, splitting interleaved container logs when requested.
+// The returned containers are empty unless the log was split.
+// With -context-percent the input sections are fitted to the budget by priority rather than cut as a whole.
+func generateLogKeyPoints(ctx context.Context, opts *Options, input KeyPointsInput, requestOptions RequestOptions, metadata *RunMetadata) (string, []ContainerLog, error) {
+	requestOptions = withModel(requestOptions, opts.KeyPointsModel)
+	delimiter, err := parseContextDelimiter(opts.ContextDelimiter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	budget, err := keyPointsBudget("Key Points", delimiter, requestOptions)
+	if err != nil {
+		return "", nil, err
+	}
+	if budget > 0 {
+		input = input.Fit(budget)
+	}
+	logString := input.String()
+
+	if opts.SplitContainers {
+		containers := splitContainerLogs(logString)
+		if len(containers) > 1 {
+			keyPoints, err := generateContainerKeyPoints(ctx, containers, delimiter, requestOptions, metadata)
+			return keyPoints, containers, err
+		}
+		verbosef("Found %d prefixed containers, analyzing the log as a whole", len(containers))
+	}
+
+	keyPoints, err := generateKeyPoints(ctx, "Key Points", logString, delimiter, requestOptions, metadata)
+	return keyPoints, nil, err
+}
+
+// Helper function to introduce the key points to the analysis or interactive session
+func keyPointsIntroduction(keyPoints string, containers []ContainerLog) string {
+	if len(containers) > 0 {
+		return "Here are the key points from the log analysis, grouped by container. " +
+			"Perform a combined cross-container analysis: separate sidecar noise from issues in the main application " +
+			"and correlate events across containers.\n\n" + keyPoints
+	}
+	return "Here are the key points from the log analysis:\n\n" + keyPoints
+}
+
+// Function to print the generated Loki query commands for a log
+func runLoki(ctx context.Context, opts *Options) error {
+	if err := validateLokiOptions(opts.Loki); err != nil {
+		return err
+	}
+
+	logFile, err := loadLog(opts)
+	if err != nil {
+		return err
+	}
+	related, err := loadRelatedLogs(opts.AlsoLogs, logFile.Path)
+	if err != nil {
+		return err
+	}
+
+	lokiQueries := generateLokiQueries(logContents(logFile, related), logFile.Window, opts.Loki)
+	for _, query := range lokiQueries {
+		fmt.Println(lokiCommand(query, opts.Loki.PrettyCurl))
+	}
+
+	// Execute the queries and print their results in query order
+	if opts.Loki.Run {
+		results, err := collectLokiResults(ctx, lokiQueries, opts.Loki.Concurrency)
+		if err != nil {
+			return err
+		}
+		fmt.Print("\n" + renderLokiResults(results))
+	}
+	return nil
+}
+
+// Function to verify the API keys and endpoint with a minimal request
+func runSelftest(ctx context.Context, opts *Options) error {
+	requestOptions, err := newRequestOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	messages := []Message{
+		{
+			Role:    "user",
+			Content: "Reply with the single word: pong",
+		},
+	}
+
+	start := time.Now()
+	result, err := sendRequest(ctx, messages, requestOptions)
+	if err != nil {
+		return fmt.Errorf("Selftest failed: %v", err)
+	}
+
+	fmt.Printf("Selftest passed: endpoint %s responded in %s (model: %s)\n",
+		requestOptions.URL, time.Since(start).Round(time.Millisecond), valueOrDash(result.Model))
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goroutineLeakThreshold is the goroutine count in a dump flagged as a possible leak
+const goroutineLeakThreshold = 1000
+
+// goroutineLongWaitMinutes is how long a goroutine must be blocked to count as long-waiting
+const goroutineLongWaitMinutes = 10
+
+var (
+	// Go goroutine dump header, e.g. "goroutine 17 [chan receive, 12 minutes]:"
+	goroutineHeaderRegex = regexp.MustCompile(`^\s*goroutine (\d+) \[([^\],]+)(?:, (\d+) minutes)?[^\]]*\]:`)
+
+	// Deadlocks reported by the Go runtime and the JVM thread dump
+	deadlockRegex = regexp.MustCompile(`all goroutines are asleep - deadlock!|Found (?:one|\d+) Java-level deadlock|sync: (?:negative WaitGroup counter|unlock of unlocked mutex)|fatal error: concurrent map (?:writes|read and map write)`)
+)
+
+// GoroutineDump represents a single goroutine dump found in a log
+type GoroutineDump struct {
+	Line        int // 1-based line number of the first goroutine
+	Count       int
+	States      map[string]int // Goroutines per state, e.g. "chan receive"
+	LongBlocked int            // Goroutines blocked for at least goroutineLongWaitMinutes
+}
+
+// ConcurrencyIssues represents the goroutine dumps and deadlock indicators found in a log
+type ConcurrencyIssues struct {
+	Dumps     []GoroutineDump
+	Deadlocks []string // Lines reporting a deadlock or fatal concurrency error
+}
+
+// Function to extract goroutine dumps and deadlock indicators from the log content.
+// A new dump starts when a goroutine ID repeats, as every dump includes goroutine 1.
+func extractConcurrencyIssues(content string) ConcurrencyIssues {
+	var issues ConcurrencyIssues
+	var current *GoroutineDump
+	seen := make(map[string]bool)
+
+	for i, line := range strings.Split(content, "\n") {
+		if deadlockRegex.MatchString(line) {
+			issues.Deadlocks = append(issues.Deadlocks, strings.TrimSpace(line))
+		}
+
+		matches := goroutineHeaderRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		if current == nil || seen[matches[1]] {
+			issues.Dumps = append(issues.Dumps, GoroutineDump{Line: i + 1, States: make(map[string]int)})
+			current = &issues.Dumps[len(issues.Dumps)-1]
+			seen = make(map[string]bool)
+		}
+		seen[matches[1]] = true
+
+		current.Count++
+		current.States[matches[2]]++
+		if minutes, err := strconv.Atoi(matches[3]); err == nil && minutes >= goroutineLongWaitMinutes {
+			current.LongBlocked++
+		}
+	}
+
+	return issues
+}
+
+// Function to check whether anything worth reporting was found
+func (c ConcurrencyIssues) Found() bool {
+	return len(c.Dumps) > 0 || len(c.Deadlocks) > 0
+}
+
+// Function to find the line of the first dump or deadlock report
+func (c ConcurrencyIssues) FirstLine(content string) int {
+	line := 0
+	if len(c.Dumps) > 0 {
+		line = c.Dumps[0].Line
+	}
+	if len(c.Deadlocks) > 0 {
+		if deadlock := lineOf(content, c.Deadlocks[0]); deadlock > 0 && (line == 0 || deadlock < line) {
+			line = deadlock
+		}
+	}
+	return line
+}
+
+// Function to render the deadlock indicators and goroutine dumps as Markdown
+func renderConcurrencyIssues(issues ConcurrencyIssues) string {
+	var sb strings.Builder
+
+	if len(issues.Deadlocks) > 0 {
+		sb.WriteString(fmt.Sprintf("**Deadlock or fatal concurrency error reported %d time(s)**, e.g. `%s`\n\n", len(issues.Deadlocks), issues.Deadlocks[0]))
+	}
+
+	if len(issues.Dumps) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("| Dump | Line | Goroutines | Top States | Blocked ≥10 min |\n")
+	sb.WriteString("|------|------|------------|------------|-----------------|\n")
+	for i, dump := range issues.Dumps {
+		count := strconv.Itoa(dump.Count)
+		if dump.Count >= goroutineLeakThreshold {
+			count += " (**possible leak**)"
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %d | %s | %s | %d |\n", i+1, dump.Line, count, topGoroutineStates(dump.States, 3), dump.LongBlocked))
+	}
+
+	// Goroutine counts growing between dumps point to a leak
+	if len(issues.Dumps) > 1 {
+		first, last := issues.Dumps[0].Count, issues.Dumps[len(issues.Dumps)-1].Count
+		if last > first {
+			sb.WriteString(fmt.Sprintf("\nGoroutine count grew from %d to %d across %d dumps.\n", first, last, len(issues.Dumps)))
+		}
+	}
+	return sb.String()
+}
+
+// Helper function to list the most common goroutine states with their counts
+func topGoroutineStates(states map[string]int, max int) string {
+	var names []string
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if states[names[i]] != states[names[j]] {
+			return states[names[i]] > states[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > max {
+		names = names[:max]
+	}
+
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, states[name]))
+	}
+	return strings.Join(parts, ", ")
+}
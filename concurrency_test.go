@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractConcurrencyIssuesGoroutineDumps(t *testing.T) {
+	content := `SIGQUIT: quit
+goroutine 1 [chan receive, 15 minutes]:
+main.main()
+goroutine 7 [select]:
+goroutine 9 [semacquire, 12 minutes]:
+--- second dump ---
+goroutine 1 [chan receive, 20 minutes]:
+goroutine 7 [select]:
+goroutine 9 [semacquire, 17 minutes]:
+goroutine 10 [IO wait]:`
+
+	issues := extractConcurrencyIssues(content)
+	if len(issues.Dumps) != 2 {
+		t.Fatalf("got %d dumps, want 2", len(issues.Dumps))
+	}
+	first := issues.Dumps[0]
+	if first.Line != 2 || first.Count != 3 || first.LongBlocked != 2 {
+		t.Errorf("first dump = %+v, want line 2, 3 goroutines, 2 long blocked", first)
+	}
+	if want := map[string]int{"chan receive": 1, "select": 1, "semacquire": 1}; !reflect.DeepEqual(first.States, want) {
+		t.Errorf("first dump states = %v, want %v", first.States, want)
+	}
+	if issues.Dumps[1].Line != 7 || issues.Dumps[1].Count != 4 {
+		t.Errorf("second dump = %+v, want line 7 with 4 goroutines", issues.Dumps[1])
+	}
+
+	if rendered := renderConcurrencyIssues(issues); !strings.Contains(rendered, "Goroutine count grew from 3 to 4 across 2 dumps.") {
+		t.Errorf("renderConcurrencyIssues =\n%s", rendered)
+	}
+}
+
+func TestExtractConcurrencyIssuesDeadlock(t *testing.T) {
+	content := `starting workers
+fatal error: all goroutines are asleep - deadlock!
+
+goroutine 1 [semacquire]:
+sync.(*WaitGroup).Wait(0xc000012345)`
+
+	issues := extractConcurrencyIssues(content)
+	if !issues.Found() || len(issues.Deadlocks) != 1 || issues.Deadlocks[0] != "fatal error: all goroutines are asleep - deadlock!" {
+		t.Fatalf("deadlocks = %v, want the Go runtime deadlock", issues.Deadlocks)
+	}
+	if line := issues.FirstLine(content); line != 2 {
+		t.Errorf("FirstLine = %d, want 2", line)
+	}
+
+	if extractConcurrencyIssues("all good\nno dumps here").Found() {
+		t.Error("Found() reported issues in a clean log")
+	}
+}
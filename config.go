@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ConfigIssue represents a group of identical configuration or environment variable errors
+type ConfigIssue struct {
+	Kind    string
+	Key     string // Environment variable, config key or file, when present
+	Count   int
+	Example string
+}
+
+// configErrorKinds maps configuration errors from Go, Node.js, Python, Java/Spring and shells to a kind,
+// most specific first. The "key" group captures the variable, property or file name.
+// Quotes may be double or single, as double quotes are replaced when the log is read.
+var configErrorKinds = []struct {
+	kind string
+	fix  string // Where the fix usually lives in Kubernetes
+	re   *regexp.Regexp
+}{
+	// "missing required environment variable DATABASE_URL", "environment variable 'PORT' is not set",
+	// Go envconfig "required key API_TOKEN missing value", Node "Missing environment variable: FOO",
+	// Python "KeyError: 'DATABASE_URL'" from os.environ, shell "FOO: parameter not set"
+	{"missing env var", "Set it in the container `env`, or from a ConfigMap/Secret with `envFrom`/`valueFrom`", regexp.MustCompile(
+		`(?:(?i:missing (?:required )?env(?:ironment)? var(?:iable)?s?:?)\s*['"]?(?P<key>[A-Za-z_][A-Za-z0-9_]*)` +
+			`|(?i:env(?:ironment)? var(?:iable)?) ['"]?(?P<key>[A-Za-z_][A-Za-z0-9_]*)['"]? (?i:(?:is )?(?:not set|not defined|undefined|missing|is required|must be set))` +
+			`|required key (?P<key>[A-Za-z_][A-Za-z0-9_]*) missing value` +
+			`|os\.environ\[['"](?P<key>[A-Za-z_][A-Za-z0-9_]*)['"]\]` +
+			`|KeyError: ['"](?P<key>[A-Z_][A-Z0-9_]*)['"]` +
+			`|\b(?P<key>[A-Z_][A-Z0-9_]*): (?:parameter (?:null or )?not set|unbound variable))`)},
+
+	// Spring "Could not resolve placeholder 'db.url' in value '${db.url}'"
+	{"unresolved placeholder", "Provide the property as an environment variable or in the mounted application config", regexp.MustCompile(
+		`Could not resolve placeholder ['"](?P<key>[^'"]+)['"]`)},
+
+	// "could not find config file /etc/app/config.yaml", viper "Config File 'config' Not Found in '[/etc/app]'",
+	// "open /etc/app/config.yaml: no such file or directory", Python "FileNotFoundError: ... 'settings.yaml'"
+	{"missing config file", "Check the ConfigMap/Secret volume mount path and `items`/`subPath` keys", regexp.MustCompile(
+		`(?i)(?:(?:could not|couldn't|cannot|can't|unable to|failed to) (?:find|load|read|open) (?:the )?config(?:uration)?(?: file)?:?\s*['"]?(?P<key>[^\s'",:]+)` +
+			`|Config File ['"](?P<key>[^'"]+)['"] Not Found` +
+			`|open (?P<key>\S*(?:conf|config|settings|application)\S*\.(?:ya?ml|json|toml|ini|properties|conf|env)): no such file or directory` +
+			`|FileNotFoundError: .*?['"](?P<key>[^'"]+\.(?:ya?ml|json|toml|ini|properties|conf|env))['"])`)},
+
+	// Go flag "invalid value 'abc' for flag -port", "invalid value for PORT", Spring "Failed to bind properties under 'server.port'"
+	{"invalid value", "Check the value set in the ConfigMap/Secret or container `env`", regexp.MustCompile(
+		`(?i)(?:invalid value ['"][^'"]*['"] for (?:flag )?-*(?P<key>[\w.\-]+)` +
+			`|invalid value for (?:config(?:uration)? |env(?:ironment)? var(?:iable)? |key |property |setting )?['"]?(?P<key>[\w.\-]+)` +
+			`|Failed to bind properties under ['"](?P<key>[^'"]+)['"]` +
+			`|invalid (?:config(?:uration)?|setting) (?:for |value for )?['"]?(?P<key>[\w.\-]+)['"]?:)`)},
+}
+
+// Function to extract configuration and environment variable errors from the log content, grouped by kind and key
+func extractConfigIssues(content string) []ConfigIssue {
+	var issues []ConfigIssue
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		for _, errorKind := range configErrorKinds {
+			matches := errorKind.re.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+
+			// Several alternatives capture the key, only the matching one is set
+			var key string
+			for i, name := range errorKind.re.SubexpNames() {
+				if name == "key" && matches[i] != "" {
+					key = strings.TrimRight(matches[i], ".")
+					break
+				}
+			}
+
+			indexKey := errorKind.kind + "|" + key
+			if i, ok := index[indexKey]; ok {
+				issues[i].Count++
+			} else {
+				index[indexKey] = len(issues)
+				issues = append(issues, ConfigIssue{Kind: errorKind.kind, Key: key, Count: 1, Example: line})
+			}
+			break
+		}
+	}
+
+	return issues
+}
+
+// Function to render the configuration issues as a Markdown table with the likely place of the fix
+func renderConfigIssues(issues []ConfigIssue) string {
+	fixes := make(map[string]string)
+	for _, errorKind := range configErrorKinds {
+		fixes[errorKind.kind] = errorKind.fix
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Kind | Key | Occurrences | Likely Fix | Example |\n")
+	sb.WriteString("|------|-----|-------------|------------|---------|\n")
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | %s | `%s` |\n",
+			issue.Kind, tableCell(valueOrDash(issue.Key), 60), issue.Count, fixes[issue.Kind], tableCell(issue.Example, 160)))
+	}
+	return sb.String()
+}
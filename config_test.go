@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractConfigIssues(t *testing.T) {
+	tests := []struct {
+		line, kind, key string
+	}{
+		{`panic: missing required environment variable DATABASE_URL`, "missing env var", "DATABASE_URL"},
+		{`Error: environment variable 'PORT' is not set`, "missing env var", "PORT"},
+		{`envconfig.Process: required key API_TOKEN missing value`, "missing env var", "API_TOKEN"},
+		{`KeyError: 'REDIS_HOST'`, "missing env var", "REDIS_HOST"},
+		{`/entrypoint.sh: line 4: SECRET_KEY: parameter not set`, "missing env var", "SECRET_KEY"},
+		{`java.lang.IllegalArgumentException: Could not resolve placeholder 'db.url' in value '${db.url}'`, "unresolved placeholder", "db.url"},
+		{`Config File 'config' Not Found in '[/etc/app]'`, "missing config file", "config"},
+		{`open /etc/app/config.yaml: no such file or directory`, "missing config file", "/etc/app/config.yaml"},
+		{`invalid value 'abc' for flag -port: parse error`, "invalid value", "port"},
+		{`Failed to bind properties under 'server.port' to java.lang.Integer`, "invalid value", "server.port"},
+	}
+	for _, tt := range tests {
+		issues := extractConfigIssues(tt.line)
+		if len(issues) != 1 || issues[0].Kind != tt.kind || issues[0].Key != tt.key {
+			t.Errorf("extractConfigIssues(%q) = %+v, want kind %q and key %q", tt.line, issues, tt.kind, tt.key)
+		}
+	}
+}
+
+func TestExtractConfigIssuesGroupsByKey(t *testing.T) {
+	content := "missing environment variable: DATABASE_URL\nretrying\nmissing environment variable: DATABASE_URL\nINFO config loaded"
+
+	issues := extractConfigIssues(content)
+	if len(issues) != 1 || issues[0].Count != 2 {
+		t.Fatalf("issues = %+v, want DATABASE_URL twice", issues)
+	}
+	if rendered := renderConfigIssues(issues); !strings.Contains(rendered, "| missing env var | DATABASE_URL | 2 | Set it in the container `env`") {
+		t.Errorf("renderConfigIssues =\n%s", rendered)
+	}
+}
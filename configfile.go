@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileConfig represents the -config file. Every field is optional.
+//
+// Precedence, from highest to lowest:
+//  1. Flags given on the command line
+//  2. Values of the -config file
+//  3. Environment variables (K8S_API_URL, K8S_MODEL)
+//  4. Built-in defaults
+//
+// The config is applied to the flags that were not given, so the environment fallbacks
+// in newRequestOptions only apply when neither the flag nor the config sets a value.
+type FileConfig struct {
+	Endpoint         string            `json:"endpoint"`
+	Model            string            `json:"model"`
+	Headers          map[string]string `json:"headers"`            // Extra request headers, e.g. a gateway tenant
+	DelayMs          *int              `json:"delay_ms"`           // Pointer so an explicit 0 is kept
+	Output           string            `json:"output"`             // Default output file of the analyze command
+	SystemPromptFile string            `json:"system_prompt_file"` // Relative to the config file
+	PromptVars       map[string]string `json:"prompt_vars"`
+}
+
+// Function to load the -config file, rejecting unknown fields so typos do not go unnoticed
+func loadFileConfig(path string) (FileConfig, error) {
+	var config FileConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("Error reading config %s: %v", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("Error parsing config %s: %v", path, err)
+	}
+
+	if config.SystemPromptFile != "" && !filepath.IsAbs(config.SystemPromptFile) {
+		config.SystemPromptFile = filepath.Join(filepath.Dir(path), config.SystemPromptFile)
+	}
+	return config, nil
+}
+
+// Function to load the -config file, if any, and apply it to the flags not given on the command line
+func applyConfigFile(fs *flag.FlagSet, opts *Options) error {
+	if opts.ConfigFile == "" {
+		return nil
+	}
+	config, err := loadFileConfig(opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	applyFileConfig(fs, opts, config)
+	return nil
+}
+
+// Function to merge the config over the defaults: a value is used when the command has the flag
+// and the flag was not given. Headers are always taken from the config, with -header applied over them.
+func applyFileConfig(fs *flag.FlagSet, opts *Options, config FileConfig) {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	unset := func(name string) bool {
+		return fs.Lookup(name) != nil && !given[name]
+	}
+
+	if config.Endpoint != "" && unset("endpoint") {
+		opts.Endpoint = config.Endpoint
+	}
+	if config.Model != "" && unset("model") {
+		opts.Model = config.Model
+	}
+	if config.DelayMs != nil && unset("delay") {
+		opts.DelayMs = *config.DelayMs
+	}
+	if config.Output != "" && unset("output") {
+		opts.OutputFile = config.Output
+	}
+	if config.SystemPromptFile != "" && unset("system-prompt-file") {
+		opts.SystemPromptFile = config.SystemPromptFile
+	}
+
+	// Sort the variables so they are set in a stable order
+	if len(config.PromptVars) > 0 && unset("prompt-var") {
+		var keys []string
+		for key := range config.PromptVars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			opts.PromptVars = append(opts.PromptVars, key+"="+config.PromptVars[key])
+		}
+	}
+
+	opts.Headers = config.Headers
+}
+
+// Function to parse repeatable "Name: value" header flags on the first colon, trimming whitespace
+func parseHeaders(flagName string, values []string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, value := range values {
+		name, headerValue, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("Error: -%s must be 'Name: value', got %q", flagName, value)
+		}
+		headers[name] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
+// Helper function to set a header, replacing any existing header of the same canonical name
+func setHeader(headers map[string]string, name, value string) {
+	for key := range headers {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) {
+			delete(headers, key)
+		}
+	}
+	headers[name] = value
+}
+
+// Helper function to add the config headers to the request headers.
+// The built-in content type and API key headers are never overridden.
+func mergeHeaders(headers, extra map[string]string) {
+	builtin := make(map[string]bool)
+	for key := range headers {
+		builtin[http.CanonicalHeaderKey(key)] = true
+	}
+	for key, value := range extra {
+		if !builtin[http.CanonicalHeaderKey(key)] {
+			headers[key] = value
+		}
+	}
+}
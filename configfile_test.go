@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Helper function to parse the request flags and apply the config file, as runCommand does
+func parseWithConfig(t *testing.T, config string, args ...string) Options {
+	t.Helper()
+	var opts Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	addRequestFlags(fs, &opts)
+	if config != "" {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"-config", path}, args...)
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if err := applyConfigFile(fs, &opts); err != nil {
+		t.Fatalf("applyConfigFile returned error: %v", err)
+	}
+	return opts
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	const config = `{"endpoint": "https://file.example.com/v1", "model": "file-model", "delay_ms": 0}`
+
+	tests := []struct {
+		name         string
+		config       string
+		env          bool
+		args         []string
+		wantEndpoint string
+		wantModel    string
+		wantDelay    int
+	}{
+		{"flag over file and env", config, true, []string{"-endpoint", "https://flag.example.com/v1", "-model", "flag-model", "-delay", "5"}, "https://flag.example.com/v1", "flag-model", 5},
+		{"file over env", config, true, nil, "https://file.example.com/v1", "file-model", 0},
+		{"env over default", "", true, nil, "https://env.example.com/v1", "env-model", 10},
+		{"default", "", false, nil, "", defaultModel, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env {
+				t.Setenv("K8S_API_URL", "https://env.example.com/v1")
+				t.Setenv("K8S_MODEL", "env-model")
+			} else {
+				t.Setenv("K8S_API_URL", "")
+				t.Setenv("K8S_MODEL", "")
+			}
+
+			opts := parseWithConfig(t, tt.config, tt.args...)
+			endpoint, err := resolveEndpoint(opts.Endpoint, true)
+			if err != nil {
+				t.Fatalf("resolveEndpoint returned error: %v", err)
+			}
+			if endpoint != tt.wantEndpoint {
+				t.Errorf("endpoint = %q, want %q", endpoint, tt.wantEndpoint)
+			}
+			if model := resolveModel(opts.Model); model != tt.wantModel {
+				t.Errorf("model = %q, want %q", model, tt.wantModel)
+			}
+			if opts.DelayMs != tt.wantDelay {
+				t.Errorf("delay = %d, want %d", opts.DelayMs, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestLoadFileConfigRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"modle": "typo"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFileConfig(path); err == nil {
+		t.Error("loadFileConfig accepted an unknown field")
+	}
+}
+
+func TestMergeHeadersKeepsBuiltinHeaders(t *testing.T) {
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "key",
+	}
+	mergeHeaders(headers, map[string]string{
+		"authorization": "Bearer other",
+		"X-Tenant":      "shop",
+	})
+
+	want := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "key",
+		"X-Tenant":      "shop",
+	}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("headers = %v, want %v", headers, want)
+	}
+}
+
+func TestParseHeadersAndSetHeader(t *testing.T) {
+	headers, err := parseHeaders("header", []string{"X-Tenant: shop", "Authorization:  Bearer abc:def "})
+	if err != nil {
+		t.Fatalf("parseHeaders returned error: %v", err)
+	}
+	if headers["X-Tenant"] != "shop" || headers["Authorization"] != "Bearer abc:def" {
+		t.Errorf("headers = %v", headers)
+	}
+	if _, err := parseHeaders("header", []string{"no colon"}); err == nil {
+		t.Error("parseHeaders accepted a value without a colon")
+	}
+
+	builtin := map[string]string{"authorization": "key"}
+	setHeader(builtin, "Authorization", "Bearer abc")
+	if !reflect.DeepEqual(builtin, map[string]string{"Authorization": "Bearer abc"}) {
+		t.Errorf("setHeader left %v", builtin)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ContainerLog represents the lines of a single container extracted from interleaved logs
+type ContainerLog struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Content   string `json:"-"`
+	Lines     int    `json:"lines"`
+}
+
+// Matches the prefix written by `kubectl logs --all-containers --prefix`, e.g. "[pod/web-1/nginx] message"
+var containerPrefixRegex = regexp.MustCompile(`^\[pod/([^/\]]+)/([^\]]+)\]\s?(.*)$`)
+
+// Function to split interleaved logs into per-container logs by their kubectl prefix.
+// Lines without a prefix (e.g. wrapped stack traces) belong to the preceding container.
+// Containers are returned in order of first appearance.
+func splitContainerLogs(content string) []ContainerLog {
+	var containers []*ContainerLog
+	var builders []*strings.Builder
+	index := make(map[string]int)
+	current := -1
+
+	for _, line := range strings.Split(content, "\n") {
+		if matches := containerPrefixRegex.FindStringSubmatch(line); matches != nil {
+			key := matches[1] + "/" + matches[2]
+			i, ok := index[key]
+			if !ok {
+				i = len(containers)
+				index[key] = i
+				containers = append(containers, &ContainerLog{Pod: matches[1], Container: matches[2]})
+				builders = append(builders, &strings.Builder{})
+			}
+			current = i
+			line = matches[3]
+		}
+
+		// Skip lines before the first prefixed line
+		if current < 0 {
+			continue
+		}
+
+		builders[current].WriteString(line)
+		builders[current].WriteString("\n")
+		containers[current].Lines++
+	}
+
+	result := make([]ContainerLog, len(containers))
+	for i, container := range containers {
+		container.Content = builders[i].String()
+		result[i] = *container
+	}
+	return result
+}
+
+// Function to generate key points for each container separately.
+// The per-container key points are combined into one Markdown document with a section per container.
+func generateContainerKeyPoints(ctx context.Context, containers []ContainerLog, delimiter ContextDelimiter, requestOptions RequestOptions, metadata *RunMetadata) (string, error) {
+	var sb strings.Builder
+
+	for _, container := range containers {
+		progressf("\nGenerating key points for container %s (pod %s)\n", container.Container, container.Pod)
+
+		passName := fmt.Sprintf("Key Points (%s)", container.Container)
+		keyPoints, err := generateKeyPoints(ctx, passName, container.Content, delimiter, requestOptions, metadata)
+		if err != nil {
+			return "", fmt.Errorf("Error generating key points for container %s: %w", container.Container, err)
+		}
+
+		sb.WriteString(fmt.Sprintf("## Container: %s (pod %s)\n\n", container.Container, container.Pod))
+		sb.WriteString(keyPoints)
+		sb.WriteString("\n\n")
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// Function to render the per-container breakdown as a Markdown table
+func renderContainerBreakdown(containers []ContainerLog) string {
+	var sb strings.Builder
+	sb.WriteString("| Pod | Container | Lines |\n")
+	sb.WriteString("|-----|-----------|-------|\n")
+	for _, container := range containers {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d |\n", container.Pod, container.Container, container.Lines))
+	}
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSplitContainerLogsInterleavedPrefixes(t *testing.T) {
+	content := "starting up\n" +
+		"[pod/web-1/nginx] GET /healthz 200\n" +
+		"[pod/web-1/app] panic: runtime error\n" +
+		"goroutine 1 [running]:\n" +
+		"[pod/web-1/nginx] GET /api 502\n" +
+		"[pod/web-2/app] listening on :8080"
+
+	containers := splitContainerLogs(content)
+
+	want := []ContainerLog{
+		{Pod: "web-1", Container: "nginx", Content: "GET /healthz 200\nGET /api 502\n", Lines: 2},
+		{Pod: "web-1", Container: "app", Content: "panic: runtime error\ngoroutine 1 [running]:\n", Lines: 2},
+		{Pod: "web-2", Container: "app", Content: "listening on :8080\n", Lines: 1},
+	}
+	if len(containers) != len(want) {
+		t.Fatalf("got %d containers, want %d: %+v", len(containers), len(want), containers)
+	}
+	for i := range want {
+		if containers[i] != want[i] {
+			t.Errorf("container %d = %+v, want %+v", i, containers[i], want[i])
+		}
+	}
+}
+
+func TestSplitContainerLogsWithoutPrefixes(t *testing.T) {
+	if containers := splitContainerLogs("plain line\nanother line"); len(containers) != 0 {
+		t.Errorf("got %+v, want no containers", containers)
+	}
+}
+
+func TestGenerateContainerKeyPointsKeepsCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	containers := []ContainerLog{{Pod: "web-1", Container: "nginx", Content: "GET / 200\n", Lines: 1}}
+	_, err := generateContainerKeyPoints(ctx, containers, ContextDelimiter{}, RequestOptions{URL: server.URL}, &RunMetadata{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want it to wrap context.Canceled", err)
+	}
+	if code := exitCode(err); code != interruptedExitCode {
+		t.Errorf("exitCode = %d, want %d", code, interruptedExitCode)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// criLineRegex matches a line of the CRI log format written under /var/log/pods:
+// "<RFC3339Nano timestamp> <stdout|stderr> <P|F> <message>", where P marks a partial line
+var criLineRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\S+) (stdout|stderr) ([PF])(?: (.*))?$`)
+
+// Function to parse CRI formatted content into "<timestamp> <message>" lines.
+// Partial (P) lines are joined per stream into the full logical line, stamped with the time of its first part.
+// Lines that are not in the CRI format are kept unchanged. The window spans the earliest to the latest timestamp.
+func parseCRILog(content string) (string, TimeWindow, int) {
+	var lines []string
+	var window TimeWindow
+	parsed := 0
+
+	// Partial lines pending per stream, as stdout and stderr fragments can interleave
+	type pendingLine struct {
+		timestamp time.Time
+		message   strings.Builder
+	}
+	pending := make(map[string]*pendingLine)
+
+	for _, line := range strings.Split(content, "\n") {
+		matches := criLineRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if matches == nil {
+			lines = append(lines, line)
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, matches[1])
+		if err != nil {
+			lines = append(lines, line)
+			continue
+		}
+		parsed++
+		timestamp = timestamp.UTC()
+		if window.Start.IsZero() || timestamp.Before(window.Start) {
+			window.Start = timestamp
+		}
+		if timestamp.After(window.End) {
+			window.End = timestamp
+		}
+
+		stream, tag, message := matches[2], matches[3], matches[4]
+		part, ok := pending[stream]
+		if !ok {
+			part = &pendingLine{timestamp: timestamp}
+		}
+		part.message.WriteString(message)
+		if tag == "P" {
+			pending[stream] = part
+			continue
+		}
+		delete(pending, stream)
+		lines = append(lines, part.timestamp.Format(time.RFC3339Nano)+" "+part.message.String())
+	}
+
+	// Flush partial lines the log ended on
+	for _, stream := range []string{"stdout", "stderr"} {
+		if part, ok := pending[stream]; ok {
+			lines = append(lines, part.timestamp.Format(time.RFC3339Nano)+" "+part.message.String())
+		}
+	}
+
+	return strings.Join(lines, "\n"), window, parsed
+}
+
+// Function to convert a CRI formatted log with -cri-format, using the CRI timestamps for its time window
+func applyCRIFormat(opts *Options, logFile LogFile) LogFile {
+	if !opts.CRIFormat {
+		return logFile
+	}
+	content, window, parsed := parseCRILog(logFile.Content)
+	if parsed == 0 {
+		verbosef("No CRI formatted lines found in %s", logFile.Path)
+		return logFile
+	}
+	verbosef("Parsed %d CRI formatted lines in %s", parsed, logFile.Path)
+
+	logFile.Content = content
+	logFile.Window = window
+	if window.Start.Equal(window.End) {
+		logFile.Window.End = window.Start.Add(5 * time.Minute)
+	}
+	return logFile
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCRILogJoinsPartialLines(t *testing.T) {
+	content := "2024-01-02T15:04:05.000000001+01:00 stdout P {'level':'error',\n" +
+		"2024-01-02T14:04:05.100Z stderr F panic: nil map\n" +
+		"2024-01-02T14:04:05.200Z stdout P 'msg':'payment \n" +
+		"2024-01-02T14:04:05.300Z stdout F failed'}\n" +
+		"not a CRI line\n" +
+		"2024-01-02T14:04:06Z stdout F\n" +
+		"2024-01-02T14:04:07Z stderr P trailing fragment"
+
+	got, window, parsed := parseCRILog(content)
+
+	want := "2024-01-02T14:04:05.1Z panic: nil map\n" +
+		"2024-01-02T14:04:05.000000001Z {'level':'error','msg':'payment failed'}\n" +
+		"not a CRI line\n" +
+		"2024-01-02T14:04:06Z \n" +
+		"2024-01-02T14:04:07Z trailing fragment"
+	if got != want {
+		t.Errorf("parseCRILog content =\n%s\nwant\n%s", got, want)
+	}
+	if parsed != 6 {
+		t.Errorf("parsed = %d, want 6", parsed)
+	}
+	wantStart := time.Date(2024, 1, 2, 14, 4, 5, 1, time.UTC)
+	wantEnd := time.Date(2024, 1, 2, 14, 4, 7, 0, time.UTC)
+	if !window.Start.Equal(wantStart) || !window.End.Equal(wantEnd) {
+		t.Errorf("window = %v - %v, want %v - %v", window.Start, window.End, wantStart, wantEnd)
+	}
+}
+
+func TestApplyCRIFormatWidensSingleTimestamp(t *testing.T) {
+	logFile := applyCRIFormat(&Options{CRIFormat: true}, LogFile{Path: "pod.log", Content: "2024-01-02T15:04:05Z stdout F ready"})
+
+	if logFile.Content != "2024-01-02T15:04:05Z ready" {
+		t.Errorf("content = %q, want the parsed line", logFile.Content)
+	}
+	if logFile.Window.End.Sub(logFile.Window.Start) != 5*time.Minute {
+		t.Errorf("window = %v - %v, want 5 minutes", logFile.Window.Start, logFile.Window.End)
+	}
+
+	plain := LogFile{Path: "app.log", Content: "plain log"}
+	if got := applyCRIFormat(&Options{CRIFormat: true}, plain); got.Content != plain.Content {
+		t.Errorf("content = %q, want a log without CRI lines unchanged", got.Content)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DeepDive represents the remediation runbook requested for a single detected issue
+type DeepDive struct {
+	Issue   string `json:"issue"`
+	Runbook string `json:"runbook"`
+}
+
+// deepDivePrompt asks for a focused runbook for one issue, following up on the analysis
+const deepDivePrompt = `Focus only on the following issue detected in this log:
+
+## %s
+
+%s
+
+Write a step-by-step remediation runbook for this specific issue: how to confirm it (kubectl commands and what to look for), how to fix it, how to verify the fix, and how to prevent it from recurring. Do not repeat the overall analysis.`
+
+// Function to send a follow-up request per detected issue asking for a remediation runbook.
+// Each follow-up builds on the analysis conversation; issues with the same title are only asked about once.
+func requestDeepDives(ctx context.Context, findings []FindingSection, analysisMessages []Message, analysis string, requestOptions RequestOptions, metadata *RunMetadata) ([]DeepDive, error) {
+	var deepDives []DeepDive
+	seen := make(map[string]bool)
+
+	for _, finding := range findings {
+		if seen[finding.Title] {
+			continue
+		}
+		seen[finding.Title] = true
+
+		progressf("\nRequesting a remediation runbook for %s...\n", finding.Title)
+		messages := append(append([]Message{}, analysisMessages...),
+			Message{Role: "assistant", Content: analysis},
+			Message{Role: "user", Content: fmt.Sprintf(deepDivePrompt, finding.Title, finding.Body)},
+		)
+
+		result, err := sendRequest(ctx, messages, requestOptions)
+		if err != nil {
+			return nil, fmt.Errorf("Error requesting deep dive for %s: %v", finding.Title, err)
+		}
+		metadata.Record(fmt.Sprintf("Deep Dive: %s", finding.Title), requestOptions.Model, result)
+		deepDives = append(deepDives, DeepDive{Issue: finding.Title, Runbook: result.Content})
+	}
+
+	return deepDives, nil
+}
+
+// Function to render the deep dives as a Markdown section with a subsection per issue
+func renderDeepDives(deepDives []DeepDive) string {
+	var sb strings.Builder
+	for _, deepDive := range deepDives {
+		sb.WriteString(fmt.Sprintf("## %s\n\n%s\n\n", deepDive.Issue, deepDive.Runbook))
+	}
+	return sb.String()
+}
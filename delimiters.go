@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContextDelimiter frames the log in the key points request
+type ContextDelimiter struct {
+	Open  string
+	Close string
+
+	// How the key points prompt refers to the framed log, replacing its "<context>" reference
+	Reference string
+}
+
+// contextDelimiterPresets lists the built-in -context-delimiter values
+var contextDelimiterPresets = map[string]ContextDelimiter{
+	"xml":       {Open: "<context>", Close: "</context>", Reference: "<context>"},
+	"backticks": {Open: "```", Close: "```", Reference: "log in the triple-backtick block"},
+	"markers":   {Open: "### LOG START ###", Close: "### LOG END ###", Reference: "log between ### LOG START ### and ### LOG END ###"},
+}
+
+// Function to resolve a -context-delimiter value: a preset name or "tag:<name>" for a custom XML tag
+func parseContextDelimiter(value string) (ContextDelimiter, error) {
+	if preset, ok := contextDelimiterPresets[value]; ok {
+		return preset, nil
+	}
+
+	if strings.HasPrefix(value, "tag:") {
+		tag := strings.TrimPrefix(value, "tag:")
+		if tag == "" || strings.ContainsAny(tag, "<>/ \t\n") {
+			return ContextDelimiter{}, fmt.Errorf("Error: invalid tag name %q in -context-delimiter", tag)
+		}
+		return ContextDelimiter{Open: "<" + tag + ">", Close: "</" + tag + ">", Reference: "<" + tag + ">"}, nil
+	}
+
+	var names []string
+	for name := range contextDelimiterPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return ContextDelimiter{}, fmt.Errorf("Error: unknown -context-delimiter %q (use %s, or tag:<name>)", value, strings.Join(names, ", "))
+}
+
+// Function to frame the log with the delimiter after the key points prompt
+func (d ContextDelimiter) Frame(prompt, logString string) string {
+	prompt = strings.ReplaceAll(prompt, "<context>", d.Reference)
+	return fmt.Sprintf("%s\n%s\n%s\n%s", prompt, d.Open, logString, d.Close)
+}
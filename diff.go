@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// A top-level Markdown heading, used to find the finding sections of a previous Markdown output
+	diffHeadingRegex = regexp.MustCompile(`(?m)^# (.+?)\s*$`)
+
+	// The severity line rendered for -analyze-json
+	diffSeverityRegex = regexp.MustCompile(`\*\*Severity\*\*: (\w+)`)
+
+	// The diff section of the previous output itself, up to the next top-level heading
+	diffSectionRegex = regexp.MustCompile(`(?s)\n# Changes Since Previous Analysis\n.*?(?:\n# |$)`)
+)
+
+// AnalysisSnapshot holds the parts of an analysis that are compared between runs
+type AnalysisSnapshot struct {
+	Severity        string
+	Issues          []string
+	Recommendations []string
+}
+
+// AnalysisDiff represents the changes of the analysis since a previous output, set with -output-diff
+type AnalysisDiff struct {
+	Previous               string   `json:"previous"`
+	Baseline               bool     `json:"baseline,omitempty"` // No previous output existed, so this run is the baseline
+	PreviousSeverity       string   `json:"previous_severity,omitempty"`
+	Severity               string   `json:"severity"`
+	NewIssues              []string `json:"new_issues,omitempty"`
+	ResolvedIssues         []string `json:"resolved_issues,omitempty"`
+	OngoingIssues          []string `json:"ongoing_issues,omitempty"`
+	AddedRecommendations   []string `json:"added_recommendations,omitempty"`
+	RemovedRecommendations []string `json:"removed_recommendations,omitempty"`
+}
+
+// Function to compare the report with the previous output at path (Markdown or JSON).
+// A missing previous output is not an error: the diff then marks this run as the baseline.
+func diffAgainstPrevious(path string, report AnalysisReport) (*AnalysisDiff, error) {
+	current := snapshotReport(report)
+	diff := &AnalysisDiff{Previous: path, Severity: current.Severity}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		diff.Baseline = true
+		return diff, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading previous analysis %s: %v", path, err)
+	}
+
+	var previous AnalysisSnapshot
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var previousReport AnalysisReport
+		if err := json.Unmarshal(data, &previousReport); err != nil {
+			return nil, fmt.Errorf("Error parsing previous analysis %s: %v", path, err)
+		}
+		previous = snapshotReport(previousReport)
+	} else {
+		previous = snapshotMarkdown(string(data))
+	}
+
+	diff.PreviousSeverity = previous.Severity
+	diff.NewIssues, diff.OngoingIssues = compareItems(current.Issues, previous.Issues)
+	diff.ResolvedIssues, _ = compareItems(previous.Issues, current.Issues)
+	diff.AddedRecommendations, _ = compareItems(current.Recommendations, previous.Recommendations)
+	diff.RemovedRecommendations, _ = compareItems(previous.Recommendations, current.Recommendations)
+	return diff, nil
+}
+
+// Function to take the compared parts of a report
+func snapshotReport(report AnalysisReport) AnalysisSnapshot {
+	snapshot := AnalysisSnapshot{Severity: reportSeverity(report)}
+	for _, finding := range report.Findings {
+		snapshot.Issues = append(snapshot.Issues, finding.Title)
+	}
+	if report.Structured != nil {
+		snapshot.Recommendations = report.Structured.Recommendations
+	} else {
+		snapshot.Recommendations = extractRecommendations(report.Analysis)
+	}
+	return snapshot
+}
+
+// Function to take the compared parts of a previous Markdown output: the finding sections
+// are its top-level headings named after a detector, the recommendations its recommendations lists
+func snapshotMarkdown(markdown string) AnalysisSnapshot {
+	// The recommendations listed in an earlier diff are not the previous run's own
+	markdown = diffSectionRegex.ReplaceAllStringFunc(markdown, func(section string) string {
+		if strings.HasSuffix(section, "\n# ") {
+			return "\n# "
+		}
+		return ""
+	})
+
+	var report AnalysisReport
+	for _, match := range diffHeadingRegex.FindAllStringSubmatch(markdown, -1) {
+		if _, ok := findingRules[match[1]]; ok {
+			report.Findings = append(report.Findings, FindingSection{Title: match[1]})
+		}
+	}
+	if match := diffSeverityRegex.FindStringSubmatch(markdown); match != nil {
+		report.Structured = &StructuredAnalysis{Severity: strings.ToLower(match[1])}
+	}
+
+	snapshot := snapshotReport(report)
+	snapshot.Recommendations = extractRecommendations(markdown)
+	return snapshot
+}
+
+// Function to collect the top-level items of the recommendations sections, without list markers or checkboxes
+func extractRecommendations(markdown string) []string {
+	var recommendations []string
+	inSection, inFence := false, false
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		switch {
+		case checklistHeadingRegex.MatchString(line):
+			inSection = true
+		case checklistSectionEndRegex.MatchString(line):
+			inSection = false
+		case inSection:
+			if loc := checklistItemRegex.FindStringIndex(line); loc != nil {
+				recommendations = append(recommendations, strings.TrimSpace(line[loc[1]:]))
+			}
+		}
+	}
+	return recommendations
+}
+
+// Helper function to split items into those missing from the others and those present in both,
+// comparing case-insensitively and ignoring Markdown emphasis and trailing punctuation
+func compareItems(items, others []string) ([]string, []string) {
+	normalize := func(item string) string {
+		item = strings.Trim(strings.ToLower(item), " *_`.;:")
+		return strings.Join(strings.Fields(item), " ")
+	}
+	seen := make(map[string]bool)
+	for _, other := range others {
+		seen[normalize(other)] = true
+	}
+
+	var missing, common []string
+	for _, item := range items {
+		if seen[normalize(item)] {
+			common = append(common, item)
+		} else {
+			missing = append(missing, item)
+		}
+	}
+	return missing, common
+}
+
+// Function to describe how the severity evolved since the previous analysis
+func (d AnalysisDiff) SeverityTrend() string {
+	previous, current := severityRanks[d.PreviousSeverity], severityRanks[d.Severity]
+	switch {
+	case previous == 0 || previous == current:
+		return "unchanged"
+	case current < previous:
+		return "improving"
+	default:
+		return "worsening"
+	}
+}
+
+// Function to render the changes since the previous analysis as Markdown
+func renderAnalysisDiff(d AnalysisDiff) string {
+	var sb strings.Builder
+	if d.Baseline {
+		sb.WriteString(fmt.Sprintf("No previous analysis was found at `%s`, so this run is the baseline for the next comparison.\n", d.Previous))
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("Compared with `%s`.\n\n", d.Previous))
+	if d.PreviousSeverity != "" && d.PreviousSeverity != d.Severity {
+		sb.WriteString(fmt.Sprintf("- **Severity**: %s → %s (%s)\n", d.PreviousSeverity, d.Severity, d.SeverityTrend()))
+	} else {
+		sb.WriteString(fmt.Sprintf("- **Severity**: %s (unchanged)\n", d.Severity))
+	}
+	sb.WriteString(fmt.Sprintf("- **New issues**: %s\n", valueOrDash(strings.Join(d.NewIssues, ", "))))
+	sb.WriteString(fmt.Sprintf("- **Resolved issues**: %s\n", valueOrDash(strings.Join(d.ResolvedIssues, ", "))))
+	sb.WriteString(fmt.Sprintf("- **Ongoing issues**: %s\n", valueOrDash(strings.Join(d.OngoingIssues, ", "))))
+
+	if len(d.AddedRecommendations) > 0 {
+		sb.WriteString("\n## New Recommendations\n\n")
+		for _, recommendation := range d.AddedRecommendations {
+			sb.WriteString("- " + recommendation + "\n")
+		}
+	}
+	if len(d.RemovedRecommendations) > 0 {
+		sb.WriteString("\n## Recommendations No Longer Given\n\n")
+		for _, recommendation := range d.RemovedRecommendations {
+			sb.WriteString("- " + recommendation + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// outputEncodings maps the supported -output-encoding values to their encoders.
+// UTF-8 maps to nil, meaning the output is written as is.
+var outputEncodings = map[string]encoding.Encoding{
+	"utf-8":  nil,
+	"utf-16": unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"latin1": charmap.ISO8859_1,
+}
+
+// Function to resolve an -output-encoding value, accepting common aliases
+func parseOutputEncoding(name string) (encoding.Encoding, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	switch normalized {
+	case "utf8":
+		normalized = "utf-8"
+	case "utf16":
+		normalized = "utf-16"
+	case "iso-8859-1", "latin-1":
+		normalized = "latin1"
+	}
+
+	enc, ok := outputEncodings[normalized]
+	if !ok {
+		return nil, fmt.Errorf("Error: unsupported -output-encoding %q (supported: utf-8, utf-16, latin1)", name)
+	}
+	return enc, nil
+}
+
+// Function to transcode UTF-8 output to the target encoding.
+// Characters the encoding cannot represent (e.g. emoji in latin1) are replaced rather than failing the run.
+func encodeOutput(data []byte, enc encoding.Encoding) ([]byte, error) {
+	if enc == nil {
+		return data, nil
+	}
+
+	encoded, err := encoding.ReplaceUnsupported(enc.NewEncoder()).Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding output: %v", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timelineMaxEvents caps the events listed per entity in the output
+const timelineMaxEvents = 20
+
+// timelinePromptEvents caps the events per entity passed to the analysis
+const timelinePromptEvents = 3
+
+var (
+	// Pod mentioned in a line, e.g. "pod web-1", "pod=web-1", "pod/web-1" or "pod: 'web-1'"
+	entityPodRegex = regexp.MustCompile(`\bpod(?:\s+|\s*[=:/]\s*)['"]?([a-z0-9][a-z0-9\-.]*[a-z0-9])\b`)
+
+	// Lines reporting an error
+	entityErrorRegex = regexp.MustCompile(`(?i)\b(error|fatal|panic|exception|failed|oomkilled|crashloopbackoff|back-off)\b`)
+)
+
+// EntityEvent is a fact extracted from a log line about a Kubernetes entity
+type EntityEvent struct {
+	Line      int       `json:"line"` // 1-based line number in the log
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	Kind      string    `json:"kind"` // restart, error or http 5xx
+	Text      string    `json:"text"`
+}
+
+// EntityTimeline is the chronology of events of a single pod or container
+type EntityTimeline struct {
+	Entity string         `json:"entity"` // "pod/<pod>/<container>" or "pod/<pod>"
+	Counts map[string]int `json:"counts"` // Events per kind
+	Events []EntityEvent  `json:"events"`
+}
+
+// Function to build the event index of a log, grouping restarts, errors and 5xx responses by the
+// pod or container they pertain to. Entities come from the kubectl --prefix (which also applies to
+// the following unprefixed lines) or from a pod named on the line. Lines without an entity are skipped.
+func buildEventIndex(content string, detectorOptions DetectorOptions) []EntityTimeline {
+	var timelines []*EntityTimeline
+	index := make(map[string]*EntityTimeline)
+	current := ""
+
+	for i, line := range strings.Split(content, "\n") {
+		entity := ""
+		if matches := containerPrefixRegex.FindStringSubmatch(line); matches != nil {
+			current = "pod/" + matches[1] + "/" + matches[2]
+			entity = current
+			line = matches[3]
+		} else if matches := entityPodRegex.FindStringSubmatch(line); matches != nil {
+			entity = "pod/" + matches[1]
+		} else {
+			entity = current
+		}
+		if entity == "" {
+			continue
+		}
+
+		kind := classifyEntityEvent(line, detectorOptions)
+		if kind == "" {
+			continue
+		}
+
+		timeline, ok := index[entity]
+		if !ok {
+			timeline = &EntityTimeline{Entity: entity, Counts: make(map[string]int)}
+			index[entity] = timeline
+			timelines = append(timelines, timeline)
+		}
+		// The timestamp has its own column, so it is dropped from the text
+		timestamp := parseLineTimestamp(line)
+		if loc := lineTimestampRegex.FindStringIndex(line); loc != nil && !timestamp.IsZero() {
+			line = line[:loc[0]] + line[loc[1]:]
+		}
+
+		timeline.Counts[kind]++
+		timeline.Events = append(timeline.Events, EntityEvent{
+			Line:      i + 1,
+			Timestamp: timestamp,
+			Kind:      kind,
+			Text:      strings.TrimSpace(line),
+		})
+	}
+
+	result := make([]EntityTimeline, len(timelines))
+	for i, timeline := range timelines {
+		// Order by time where known, keeping log order for lines without a timestamp
+		sort.SliceStable(timeline.Events, func(a, b int) bool {
+			ta, tb := timeline.Events[a].Timestamp, timeline.Events[b].Timestamp
+			return !ta.IsZero() && !tb.IsZero() && ta.Before(tb)
+		})
+		result[i] = *timeline
+	}
+	return result
+}
+
+// Helper function to classify a log line as a restart, a 5xx response or an error
+func classifyEntityEvent(line string, detectorOptions DetectorOptions) string {
+	for _, marker := range detectorOptions.RestartMarkers {
+		if marker.MatchString(line) {
+			return "restart"
+		}
+	}
+	if code := accessLogStatus(line, detectorOptions.AccessLogPatterns); code >= 500 {
+		return "http 5xx"
+	}
+	if entityErrorRegex.MatchString(line) {
+		return "error"
+	}
+	return ""
+}
+
+// Helper function to summarize the event counts of an entity, e.g. "2 restart, 14 error"
+func (t EntityTimeline) Summary() string {
+	var parts []string
+	for _, kind := range []string{"restart", "error", "http 5xx"} {
+		if count := t.Counts[kind]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, kind))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Helper function to select the events worth listing: every restart and the first of each distinct
+// message, dropping repeats of the same line, up to max events
+func (t EntityTimeline) KeyEvents(max int) []EntityEvent {
+	var events []EntityEvent
+	seen := make(map[string]bool)
+	for _, event := range t.Events {
+		key := event.Kind + "|" + event.Text
+		if event.Kind != "restart" && seen[key] {
+			continue
+		}
+		seen[key] = true
+		if len(events) < max {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Helper function to format an event time, or a dash when the line has no timestamp
+func formatEventTime(event EntityEvent) string {
+	if event.Timestamp.IsZero() {
+		return "-"
+	}
+	return event.Timestamp.UTC().Format(time.RFC3339)
+}
+
+// Function to render the per-entity timelines as Markdown, with a table of key events per entity
+func renderEventIndex(timelines []EntityTimeline) string {
+	var sb strings.Builder
+	for _, timeline := range timelines {
+		sb.WriteString(fmt.Sprintf("## %s\n\n%s.\n\n", timeline.Entity, timeline.Summary()))
+		sb.WriteString("| Time | Line | Event | Details |\n")
+		sb.WriteString("|------|------|-------|---------|\n")
+		for _, event := range timeline.KeyEvents(timelineMaxEvents) {
+			sb.WriteString(fmt.Sprintf("| %s | %d | %s | %s |\n", formatEventTime(event), event.Line, event.Kind, tableCell(event.Text, 120)))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Function to render a condensed chronology per entity for the analysis prompt
+func renderEventIndexForPrompt(timelines []EntityTimeline) string {
+	if len(timelines) < 2 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\nChronology of events per pod/container, extracted locally from the raw log. Use it to correlate events across entities:\n")
+	for _, timeline := range timelines {
+		sb.WriteString(fmt.Sprintf("\n- %s: %s\n", timeline.Entity, timeline.Summary()))
+		for _, event := range timeline.KeyEvents(timelinePromptEvents) {
+			sb.WriteString(fmt.Sprintf("  - %s line %d, %s: %s\n", formatEventTime(event), event.Line, event.Kind, tableCell(event.Text, 200)))
+		}
+	}
+	return sb.String()
+}
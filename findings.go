@@ -0,0 +1,130 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FindingSection is a group of locally detected log findings, rendered as a Markdown
+// section of the output and passed to the analysis for targeted recommendations
+type FindingSection struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`           // Markdown table
+	Line  int    `json:"line,omitempty"` // Approximate 1-based line where the issue first appears, when known
+}
+
+// DetectorOptions holds the settings of the local detectors
+type DetectorOptions struct {
+	RestartMarkers    []*regexp.Regexp
+	AccessLogPatterns []*regexp.Regexp
+}
+
+// Function to build the detector options from the flags
+func newDetectorOptions(opts *Options) (DetectorOptions, error) {
+	markers, err := restartMarkers(opts.RestartMarker)
+	if err != nil {
+		return DetectorOptions{}, err
+	}
+	accessLogs, err := accessLogPatterns(opts.AccessLogPattern)
+	if err != nil {
+		return DetectorOptions{}, err
+	}
+	return DetectorOptions{RestartMarkers: markers, AccessLogPatterns: accessLogs}, nil
+}
+
+// Function to run the local detectors over the log content.
+// Sections are only returned for detectors that found something.
+func detectFindings(logContent string, detectorOptions DetectorOptions) []FindingSection {
+	var sections []FindingSection
+
+	// A single start is not a restart
+	if restarts := extractRestarts(logContent, detectorOptions.RestartMarkers); len(restarts.Starts) > 1 {
+		sections = append(sections, FindingSection{Title: "Restarts", Body: renderRestarts(restarts), Line: restarts.Starts[0].Line})
+	}
+
+	if issues := extractNetworkIssues(logContent); len(issues) > 0 {
+		sections = append(sections, FindingSection{Title: "Network Issues", Body: renderNetworkIssues(issues), Line: lineOf(logContent, issues[0].Example)})
+	}
+
+	if issues := extractTLSIssues(logContent); len(issues) > 0 {
+		sections = append(sections, FindingSection{Title: "TLS/Certificate Issues", Body: renderTLSIssues(issues), Line: lineOf(logContent, issues[0].Example)})
+	}
+
+	if failures := extractSchedulingFailures(logContent); len(failures) > 0 {
+		sections = append(sections, FindingSection{Title: "Scheduling Failures", Body: renderSchedulingFailures(failures), Line: lineOf(logContent, failures[0].Example)})
+	}
+
+	if issues := extractStorageIssues(logContent); len(issues) > 0 {
+		sections = append(sections, FindingSection{Title: "Storage Issues", Body: renderStorageIssues(issues), Line: lineOf(logContent, issues[0].Example)})
+	}
+
+	if denials := extractAdmissionDenials(logContent); len(denials) > 0 {
+		sections = append(sections, FindingSection{Title: "Admission Denials", Body: renderAdmissionDenials(denials), Line: lineOf(logContent, denials[0].Example)})
+	}
+
+	if issues := extractConfigIssues(logContent); len(issues) > 0 {
+		sections = append(sections, FindingSection{Title: "Configuration Issues", Body: renderConfigIssues(issues), Line: lineOf(logContent, issues[0].Example)})
+	}
+
+	if issues := extractReconcileIssues(logContent); len(issues) > 0 {
+		sections = append(sections, FindingSection{Title: "Reconciliation Issues", Body: renderReconcileIssues(issues), Line: lineOf(logContent, issues[0].Example)})
+	}
+
+	if issues := extractThrottlingIssues(logContent, detectorOptions.AccessLogPatterns); len(issues) > 0 {
+		sections = append(sections, FindingSection{Title: "Throttling", Body: renderThrottlingIssues(issues), Line: lineOf(logContent, issues[0].Example)})
+	}
+
+	if issues := extractConcurrencyIssues(logContent); issues.Found() {
+		sections = append(sections, FindingSection{Title: "Concurrency Issues", Body: renderConcurrencyIssues(issues), Line: issues.FirstLine(logContent)})
+	}
+
+	if statuses := extractHTTPStatuses(logContent, detectorOptions.AccessLogPatterns); statuses.Total > 0 {
+		sections = append(sections, FindingSection{Title: "HTTP Status Codes", Body: renderHTTPStatuses(statuses)})
+	}
+
+	return sections
+}
+
+// Helper function to find the 1-based line of the first occurrence of an example line, or 0 if not found
+func lineOf(content, example string) int {
+	for i, line := range strings.Split(content, "\n") {
+		if line == example || strings.TrimSpace(line) == strings.TrimSpace(example) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Function to render the finding sections for inclusion in the analysis prompt
+func renderFindingsForPrompt(sections []FindingSection) string {
+	if len(sections) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\nThe following issues were detected locally in the raw log. Use them to give targeted recommendations:\n")
+	for _, section := range sections {
+		sb.WriteString("\n## " + section.Title + "\n\n")
+		sb.WriteString(section.Body)
+	}
+	return sb.String()
+}
+
+// Function to render the finding sections as top-level sections of the output
+func renderFindingsForOutput(sections []FindingSection) string {
+	var sb strings.Builder
+	for _, section := range sections {
+		sb.WriteString("\n\n# " + section.Title + "\n\n")
+		sb.WriteString(section.Body)
+	}
+	return sb.String()
+}
+
+// Helper function to shorten a log line and make it safe for a Markdown table cell
+func tableCell(text string, maxLen int) string {
+	text = strings.TrimSpace(text)
+	if len(text) > maxLen {
+		text = text[:maxLen] + "…"
+	}
+	return strings.ReplaceAll(text, "|", `\|`)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// severityGateExitCode distinguishes a failed -fail-on-severity gate from an error of the run
+const severityGateExitCode = 3
+
+// SeverityGateError is returned when an analysis meets the -fail-on-severity threshold
+type SeverityGateError struct {
+	Severity  string
+	Threshold string
+	LogFile   string
+}
+
+func (e *SeverityGateError) Error() string {
+	return fmt.Sprintf("Severity gate failed: %s is %s, at or above the -fail-on-severity threshold %s", e.LogFile, e.Severity, e.Threshold)
+}
+
+// Function to parse the -fail-on-severity threshold, accepting the analysis severities
+// and the SARIF levels of the local findings (error, warning, note)
+func parseSeverityThreshold(value string) (string, error) {
+	threshold := strings.ToLower(strings.TrimSpace(value))
+	if threshold == "" {
+		return "", nil
+	}
+	if severity, ok := levelSeverities[threshold]; ok {
+		return severity, nil
+	}
+	if _, ok := severityRanks[threshold]; !ok {
+		return "", fmt.Errorf("Error: invalid -fail-on-severity %q: use critical, high, medium, low, info, error, warning or note", value)
+	}
+	return threshold, nil
+}
+
+// Function to check the analyzed reports against the threshold, reporting the most severe one
+func checkSeverityGate(threshold string, reports []AnalysisReport) error {
+	if threshold == "" {
+		return nil
+	}
+
+	var worst *SeverityGateError
+	for _, report := range reports {
+		severity := reportSeverity(report)
+		if severityRanks[severity] < severityRanks[threshold] {
+			continue
+		}
+		if worst == nil || severityRanks[severity] > severityRanks[worst.Severity] {
+			worst = &SeverityGateError{Severity: severity, Threshold: threshold, LogFile: report.LogFile}
+		}
+	}
+	if worst == nil {
+		verbosef("Severity gate passed: no analysis at or above %s", threshold)
+		return nil
+	}
+	return worst
+}
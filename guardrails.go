@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// piiExitCode distinguishes PII detected with -fail-on-pii from an error of the run
+const piiExitCode = 4
+
+// PIIDetectedError is returned with -fail-on-pii when the gateway's guardrails flagged a response
+type PIIDetectedError struct {
+	Model      string
+	Guardrails GuardrailsResults
+}
+
+func (e *PIIDetectedError) Error() string {
+	return fmt.Sprintf("PII check failed: the response of %s %s (-fail-on-pii)", e.Model, describeGuardrails(e.Guardrails))
+}
+
+// Function to check whether the guardrails modified the response or found PII in it
+func (g GuardrailsResults) Flagged() bool {
+	return g.RedactedResponse || g.Presidio.FoundPII
+}
+
+// Helper function to describe what the guardrails flagged
+func describeGuardrails(g GuardrailsResults) string {
+	var flags []string
+	if g.Presidio.FoundPII {
+		flags = append(flags, "contained PII")
+	}
+	if g.RedactedResponse {
+		flags = append(flags, "was redacted by the gateway")
+	}
+	return strings.Join(flags, " and ")
+}
+
+// Function to print a prominent warning on stderr, so operators know the response
+// was modified or contained PII before it is shared or saved
+func warnGuardrails(model string, g GuardrailsResults) {
+	banner := strings.Repeat("!", 72)
+	fmt.Fprintf(os.Stderr, "\n%s\nWARNING: the response of %s %s.\nReview the output before sharing it.\n%s\n", banner, model, describeGuardrails(g), banner)
+}
+
+// Function to merge the guardrails of a stream chunk into those of the whole response
+func mergeGuardrails(current *GuardrailsResults, chunk GuardrailsResults) *GuardrailsResults {
+	if current == nil {
+		return &chunk
+	}
+	current.RedactedResponse = current.RedactedResponse || chunk.RedactedResponse
+	current.Positive = current.Positive || chunk.Positive
+	current.Presidio.FoundPII = current.Presidio.FoundPII || chunk.Presidio.FoundPII
+	return current
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// httpErrorRateThreshold is the share of 5xx responses flagged as elevated
+const httpErrorRateThreshold = 0.05
+
+// defaultAccessLogPatterns match the status code of common access log formats.
+// The first capture group (or the group named "status") is the status code.
+// Quotes may be single or double, as readLog replaces double quotes.
+var defaultAccessLogPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`['"][A-Z]+ \S+ HTTP/[\d.]+['"] (\d{3})\b`),                                                    // NGINX, Apache common/combined, ingress-nginx
+	regexp.MustCompile(`['"](?:status|status_code|statusCode|http_status|httpStatus)['"]\s*:\s*['"]?(\d{3})\b`),       // JSON access logs
+	regexp.MustCompile(`\b(?:status|status_code|statusCode|http_status|httpStatus)=['"]?(\d{3})\b`),                   // logfmt, Go and Envoy access logs
+	regexp.MustCompile(`\b(?:GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS) \S+ (?:HTTP/[\d.]+ )?(?:-> )?(\d{3})\b(?:\s|$)`), // Plain "GET /path 200" lines
+}
+
+// HTTPStatusSummary represents the distribution of HTTP status codes in a log
+type HTTPStatusSummary struct {
+	Total   int
+	Classes map[int]int // Count per class, e.g. 5 for 5xx
+	Codes   map[int]int // Count per status code
+}
+
+// Function to compile the -access-log-pattern flag, falling back to the common access log formats
+func accessLogPatterns(pattern string) ([]*regexp.Regexp, error) {
+	if pattern == "" {
+		return defaultAccessLogPatterns, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing -access-log-pattern: %v", err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("Error: -access-log-pattern must capture the status code in a group")
+	}
+	return []*regexp.Regexp{re}, nil
+}
+
+// Function to tally the HTTP status codes in the log content, counting each line once
+func extractHTTPStatuses(content string, patterns []*regexp.Regexp) HTTPStatusSummary {
+	summary := HTTPStatusSummary{Classes: make(map[int]int), Codes: make(map[int]int)}
+
+	for _, line := range strings.Split(content, "\n") {
+		code := accessLogStatus(line, patterns)
+		if code == 0 {
+			continue
+		}
+		summary.Total++
+		summary.Classes[code/100]++
+		summary.Codes[code]++
+	}
+
+	return summary
+}
+
+// Helper function to extract the HTTP status code of an access log line, or 0 when absent
+func accessLogStatus(line string, patterns []*regexp.Regexp) int {
+	for _, pattern := range patterns {
+		matches := pattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		// Prefer the group named "status", falling back to the first group
+		group := 1
+		if i := pattern.SubexpIndex("status"); i > 0 {
+			group = i
+		}
+		if code, err := strconv.Atoi(matches[group]); err == nil && code >= 100 && code <= 599 {
+			return code
+		}
+	}
+	return 0
+}
+
+// Function to compute the share of 5xx responses
+func (s HTTPStatusSummary) ErrorRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Classes[5]) / float64(s.Total)
+}
+
+// Function to render the status code distribution as a summary line and a Markdown table per class
+func renderHTTPStatuses(summary HTTPStatusSummary) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d HTTP responses in this log, 5xx rate %.1f%%", summary.Total, summary.ErrorRate()*100))
+	if summary.ErrorRate() >= httpErrorRateThreshold {
+		sb.WriteString(fmt.Sprintf(" (**elevated**, above %.0f%%)", httpErrorRateThreshold*100))
+	}
+	sb.WriteString(".\n\n")
+
+	sb.WriteString("| Class | Count | Share | Status Codes |\n")
+	sb.WriteString("|-------|-------|-------|--------------|\n")
+	for class := 5; class >= 1; class-- {
+		count := summary.Classes[class]
+		if count == 0 {
+			continue
+		}
+
+		// List the codes of the class, most frequent first
+		var codes []int
+		for code := range summary.Codes {
+			if code/100 == class {
+				codes = append(codes, code)
+			}
+		}
+		sort.Slice(codes, func(i, j int) bool {
+			if summary.Codes[codes[i]] != summary.Codes[codes[j]] {
+				return summary.Codes[codes[i]] > summary.Codes[codes[j]]
+			}
+			return codes[i] < codes[j]
+		})
+		var parts []string
+		for _, code := range codes {
+			parts = append(parts, fmt.Sprintf("%d (%d)", code, summary.Codes[code]))
+		}
+
+		sb.WriteString(fmt.Sprintf("| %dxx | %d | %.1f%% | %s |\n",
+			class, count, float64(count)/float64(summary.Total)*100, strings.Join(parts, ", ")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractHTTPStatusesDefaultFormats(t *testing.T) {
+	content := `10.0.0.1 - - [02/Jan/2024:15:04:05 +0000] 'GET /api/orders HTTP/1.1' 200 512 '-' 'curl/8.0'
+10.0.0.1 - - [02/Jan/2024:15:04:06 +0000] 'POST /api/orders HTTP/1.1' 503 0 '-' 'curl/8.0'
+{'level':'info','path':'/healthz','status':200}
+level=info method=GET path=/api/cart status=404 duration=3ms
+GET /api/cart 502
+INFO cache warmed with 200 entries`
+
+	summary := extractHTTPStatuses(content, defaultAccessLogPatterns)
+	if summary.Total != 5 {
+		t.Errorf("total = %d, want 5", summary.Total)
+	}
+	if want := map[int]int{200: 2, 503: 1, 404: 1, 502: 1}; !reflect.DeepEqual(summary.Codes, want) {
+		t.Errorf("codes = %v, want %v", summary.Codes, want)
+	}
+	if summary.ErrorRate() != 0.4 {
+		t.Errorf("error rate = %v, want 0.4", summary.ErrorRate())
+	}
+
+	rendered := renderHTTPStatuses(summary)
+	if !strings.Contains(rendered, "5xx rate 40.0% (**elevated**, above 5%)") || !strings.Contains(rendered, "| 5xx | 2 | 40.0% | 502 (1), 503 (1) |") {
+		t.Errorf("renderHTTPStatuses =\n%s", rendered)
+	}
+}
+
+func TestAccessLogPatternFlag(t *testing.T) {
+	patterns, err := accessLogPatterns(`rc=(?P<status>\d{3})`)
+	if err != nil {
+		t.Fatalf("accessLogPatterns returned error: %v", err)
+	}
+
+	summary := extractHTTPStatuses("req=1 rc=500\nreq=2 rc=201\nstatus=404", patterns)
+	if summary.Total != 2 || summary.Codes[500] != 1 || summary.Codes[201] != 1 {
+		t.Errorf("summary = %+v, want only the rc= codes", summary)
+	}
+
+	if _, err := accessLogPatterns(`rc=\d{3}`); err == nil {
+		t.Error("accessLogPatterns accepted a pattern without a capture group")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/abadojack/whatlanggo"
+)
+
+// analysisLanguage is the language the analysis is written in (American English, see the system prompt)
+var analysisLanguage = whatlanggo.Eng
+
+// languageSampleChars caps how much log text is fed to the detector
+const languageSampleChars = 20000
+
+// LanguageInfo represents the dominant language detected in the log messages
+type LanguageInfo struct {
+	Code       string  `json:"code"` // ISO 639-1
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+	Reliable   bool    `json:"reliable"`
+}
+
+// Function to detect the dominant language of the log messages.
+// Only the words are kept, so timestamps, IDs and punctuation do not skew the detection.
+func detectLanguage(content string) LanguageInfo {
+	var sb strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		for _, field := range strings.Fields(line) {
+			word := strings.TrimFunc(field, func(r rune) bool { return !unicode.IsLetter(r) })
+			if len([]rune(word)) < 2 || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+				continue
+			}
+			sb.WriteString(word)
+			sb.WriteString(" ")
+		}
+		if sb.Len() >= languageSampleChars {
+			break
+		}
+	}
+
+	info := whatlanggo.Detect(sb.String())
+	return LanguageInfo{
+		Code:       info.Lang.Iso6391(),
+		Name:       info.Lang.String(),
+		Confidence: info.Confidence,
+		Reliable:   info.IsReliable(),
+	}
+}
+
+// Function to check whether the detected language differs from the analysis language
+func (l LanguageInfo) Foreign() bool {
+	return l.Reliable && l.Code != "" && l.Code != analysisLanguage.Iso6391()
+}
+
+// Function to render the translation instruction added to the prompt for logs in another language
+func renderLanguageForPrompt(language *LanguageInfo) string {
+	if language == nil || !language.Foreign() {
+		return ""
+	}
+	return fmt.Sprintf("\n\nThe log messages are mostly in %s. When you quote log lines in your answer, "+
+		"keep the original line and add an English translation right after it.", language.Name)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lineNumbersIntroduction explains the numbered log lines sent with -include-line-numbers
+const lineNumbersIntroduction = "The lines of the log file below are prefixed with their line number in that file (e.g. '42: '). " +
+	"Keep these line numbers when you mention log events, citing them as 'line 42'.\n\n"
+
+// lineNumbersPrompt asks the analysis to cite the line numbers carried by the key points
+const lineNumbersPrompt = "\n\nWhen you refer to specific log events, cite their line numbers from the key points (e.g. 'line 42') so operators can jump to them."
+
+// Function to prefix each log line with its 1-based line number in the file for -include-line-numbers,
+// counting the lines skipped before the content (offset) by -since-file or -since-restart.
+// The number goes after a kubectl '[pod/<pod>/<container>]' prefix so containers can still be split.
+// Only the copy sent to the model is numbered; the time window and Loki queries use the original content.
+func numberLines(content string, offset int) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if matches := containerPrefixRegex.FindStringSubmatchIndex(line); matches != nil {
+			prefix := strings.TrimRight(line[:matches[6]], " ")
+			lines[i] = fmt.Sprintf("%s %d: %s", prefix, offset+i+1, line[matches[6]:])
+			continue
+		}
+		lines[i] = fmt.Sprintf("%d: %s", offset+i+1, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Function to render the note explaining the line citations of the analysis
+func renderLineNumbersNote(logFile string) string {
+	return fmt.Sprintf("> Line references such as \"line 42\" point to the line numbers of `%s`.\n\n", logFile)
+}
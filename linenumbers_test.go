@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestNumberLinesCountsSkippedLines(t *testing.T) {
+	content := "[pod/web-1/app] starting\nplain line"
+	want := "[pod/web-1/app] 41: starting\n42: plain line"
+	if numbered := numberLines(content, 40); numbered != want {
+		t.Errorf("numberLines = %q, want %q", numbered, want)
+	}
+}
+
+func TestReadLogCountsLinesBeforeSinceFileOffset(t *testing.T) {
+	discardProgress(t)
+	dir := t.TempDir()
+	logPath, sinceFile := filepath.Join(dir, "app.log"), filepath.Join(dir, "since.json")
+
+	ioutil.WriteFile(logPath, []byte("first\nsecond\n"), 0644)
+	logFile, err := readLog(logPath, sinceFile)
+	if err != nil {
+		t.Fatalf("readLog returned error: %v", err)
+	}
+	if err := saveSinceMarker(sinceFile, logPath, *logFile.since); err != nil {
+		t.Fatalf("saveSinceMarker returned error: %v", err)
+	}
+
+	ioutil.WriteFile(logPath, []byte("first\nsecond\nthird\nfourth"), 0644)
+	logFile, err = readLog(logPath, sinceFile)
+	if err != nil {
+		t.Fatalf("readLog returned error: %v", err)
+	}
+	if logFile.Content != "third\nfourth" || logFile.LineOffset != 2 {
+		t.Errorf("content = %q at line offset %d, want the appended lines after 2 lines", logFile.Content, logFile.LineOffset)
+	}
+	if numbered := numberLines(logFile.Content, logFile.LineOffset); numbered != "3: third\n4: fourth" {
+		t.Errorf("numberLines = %q, want the file's line numbers", numbered)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"golang.org/x/term"
+)
+
+// LiveRenderer periodically re-renders streamed Markdown in place on the terminal,
+// showing the raw deltas between renders
+type LiveRenderer struct {
+	out   io.Writer
+	width int
+	theme string
+	wrap  int // Word-wrap width of the renders, which -width may set apart from the terminal width
+
+	// Everything written since the start of the response, used to erase it before a render
+	written strings.Builder
+
+	// Length of the streamed content at the last render, so unchanged content is not re-rendered
+	rendered int
+}
+
+// Function to create a live renderer when partial renders are enabled and the output is a terminal.
+// It returns nil otherwise, falling back to rendering only once the stream is complete.
+func newLiveRenderer(opts RequestOptions) *LiveRenderer {
+	if opts.RenderInterval <= 0 || opts.Raw || quiet || progressOut != io.Writer(os.Stdout) || !isTerminal(os.Stdout) {
+		return nil
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return nil
+	}
+	return &LiveRenderer{out: os.Stdout, width: width, theme: opts.Theme, wrap: opts.Width}
+}
+
+// Function to write a raw delta, tracking it so it can be erased by the next render
+func (r *LiveRenderer) Write(delta string) {
+	r.written.WriteString(delta)
+	fmt.Fprint(r.out, delta)
+}
+
+// Function to replace everything written so far with the rendered Markdown of the content
+func (r *LiveRenderer) Render(content string, fix bool) error {
+	if len(content) == r.rendered {
+		return nil
+	}
+	renderedOutput, err := renderMarkdown(applyMarkdownFixes(content, fix), r.theme, r.wrap)
+	if err != nil {
+		return fmt.Errorf("Error rendering Markdown: %v", err)
+	}
+	r.erase()
+	r.Write(renderedOutput)
+	r.rendered = len(content)
+	return nil
+}
+
+// Helper function to move the cursor back to the start of the response and clear the rest of the screen
+func (r *LiveRenderer) erase() {
+	// Count the terminal rows used, including wrapped lines
+	lines := strings.Split(r.written.String(), "\n")
+	rows := 0
+	for _, line := range lines {
+		if width := ansi.StringWidth(line); width > 0 {
+			rows += (width + r.width - 1) / r.width
+		} else {
+			rows++
+		}
+	}
+
+	fmt.Fprint(r.out, "\r")
+	if rows > 1 {
+		fmt.Fprintf(r.out, "\033[%dA", rows-1)
+	}
+	fmt.Fprint(r.out, "\033[J")
+	r.written.Reset()
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lokiMaxLimit is Loki's default max_entries_limit_per_query
+const lokiMaxLimit = 5000
+
+// lokiMaxPods caps the number of per-pod queries generated for a single log
+const lokiMaxPods = 10
+
+// lokiDefaultURL is the query_range endpoint of the Loki gateway
+const lokiDefaultURL = "https://loki-gatewayK8s.K8s.cloud/loki/api/v1/query_range"
+
+// LokiOptions holds the settings used when generating Loki queries
+type LokiOptions struct {
+	URL       string
+	Limit     int
+	Direction string // "forward" or "backward"
+
+	// Format the generated curl commands over several lines
+	PrettyCurl bool
+
+	// Execute the generated queries, at most Concurrency at a time
+	Run         bool
+	Concurrency int
+
+	// Fetch surrounding context from Loki and prepend it to the analysis input
+	Prepend       bool
+	ContextTokens int // Token budget for the fetched context
+}
+
+// LokiQuery represents a query_range request against the Loki gateway.
+// It can be executed directly or rendered as a curl command for the Markdown output.
+type LokiQuery struct {
+	URL       string    `json:"url"`
+	Query     string    `json:"query"` // LogQL stream selector, e.g. {namespace="shop", pod="web-7d9f"}
+	Start     time.Time `json:"start"` // Zero when the log has no timestamps
+	End       time.Time `json:"end"`
+	Limit     int       `json:"limit"`
+	Direction string    `json:"direction"`
+}
+
+// Function to build the query_range URL parameters of the query
+func (q LokiQuery) Params() url.Values {
+	params := url.Values{}
+	params.Set("query", q.Query)
+	params.Set("limit", strconv.Itoa(q.Limit))
+	params.Set("direction", q.Direction)
+	if !q.Start.IsZero() {
+		params.Set("start", q.Start.Format(time.RFC3339Nano))
+	}
+	if !q.End.IsZero() {
+		params.Set("end", q.End.Format(time.RFC3339Nano))
+	}
+	return params
+}
+
+// Function to format the query as a curl command that can be run manually
+func (q LokiQuery) Command() string {
+	return fmt.Sprintf(`curl -G '%s' --data-urlencode '%s'`, q.URL, q.Params().Encode())
+}
+
+// Function to format the query as a multi-line curl command, with each parameter
+// on its own --data-urlencode line so the command is easy to read and review
+func (q LokiQuery) PrettyCommand() string {
+	params := q.Params()
+	var keys []string
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := []string{fmt.Sprintf("curl -G %s", shellQuote(q.URL))}
+	for _, key := range keys {
+		for _, value := range params[key] {
+			lines = append(lines, fmt.Sprintf("  --data-urlencode %s", shellQuote(key+"="+value)))
+		}
+	}
+	return strings.Join(lines, " \\\n")
+}
+
+// Helper function to single-quote a value for the shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Function to validate the Loki options against what the query_range API accepts
+func validateLokiOptions(lokiOptions LokiOptions) error {
+	if lokiOptions.Limit < 1 || lokiOptions.Limit > lokiMaxLimit {
+		return fmt.Errorf("Error: -loki-limit must be between 1 and %d, got %d", lokiMaxLimit, lokiOptions.Limit)
+	}
+	if lokiOptions.Direction != "forward" && lokiOptions.Direction != "backward" {
+		return fmt.Errorf("Error: -loki-direction must be 'forward' or 'backward', got %q", lokiOptions.Direction)
+	}
+	if lokiOptions.Concurrency < 1 {
+		return fmt.Errorf("Error: -loki-concurrency must be at least 1, got %d", lokiOptions.Concurrency)
+	}
+	if lokiOptions.URL == "" {
+		return fmt.Errorf("Error: -loki-url must not be empty")
+	}
+	if lokiOptions.Prepend && lokiOptions.ContextTokens < 1 {
+		return fmt.Errorf("Error: -loki-context-tokens must be positive, got %d", lokiOptions.ContextTokens)
+	}
+	return nil
+}
+
+// TimeWindow is the time range covered by a log
+type TimeWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Function to compute the time window of a log.
+// It must be computed from the full content, before the log is split into parts
+// (e.g. per container), so timestamps split across parts do not narrow the range.
+func logTimeWindow(content string) TimeWindow {
+	startTime, endTime := extractTimestamps(content)
+	return TimeWindow{Start: startTime, End: endTime}
+}
+
+// Function to format the query as a curl command, multi-line with -prettify-curl
+func lokiCommand(query LokiQuery, pretty bool) string {
+	if pretty {
+		return query.PrettyCommand()
+	}
+	return query.Command()
+}
+
+// Function to generate the Loki queries for the namespaces, pods and containers found in the logs.
+// Each log contributes a query per distinct pod in its namespace, or a single namespace query when no pod is found,
+// narrowed to the container when one is named, as a pod may run several.
+// Duplicate selectors across logs are dropped and at most lokiMaxPods queries are built.
+func generateLokiQueries(logContents []string, window TimeWindow, lokiOptions LokiOptions) []LokiQuery {
+	var queries []LokiQuery
+	seen := make(map[string]bool)
+
+	// Use the time window computed from the full primary log
+	startTime, endTime := window.Start, window.End
+
+	for _, logContent := range logContents {
+		// Extract relevant information from the log content
+		namespace := extractValue(logContent, `namespace (\w[\w\-]*)`)
+		podNames := extractValues(logContent, `pod (\w[\w\-]*)`, lokiMaxPods)
+		container := extractValue(logContent, lokiContainerPattern)
+		if len(podNames) == 0 {
+			podNames = []string{""}
+		}
+
+		for _, podName := range podNames {
+			// Build the stream selector
+			var selector string
+			if namespace != "" {
+				selector = fmt.Sprintf(`{namespace="%s"`, namespace)
+			} else {
+				selector = `{`
+			}
+
+			if podName != "" {
+				selector += fmt.Sprintf(`, pod="%s"`, podName)
+			}
+
+			if container != "" {
+				selector += fmt.Sprintf(`, container="%s"`, container)
+			}
+
+			selector += "}"
+
+			// Skip selectors already queried for another log
+			if seen[selector] || len(queries) >= lokiMaxPods {
+				continue
+			}
+			seen[selector] = true
+			queries = append(queries, LokiQuery{
+				URL:       lokiOptions.URL,
+				Query:     selector,
+				Start:     startTime,
+				End:       endTime,
+				Limit:     lokiOptions.Limit,
+				Direction: lokiOptions.Direction,
+			})
+		}
+	}
+
+	return queries
+}
+
+// Matches a container name only where it is delimited, so prose such as "container image" is not taken for one:
+// container="app" or container: app in structured logs, container 'app' in quoted messages,
+// and kubelet's "failed container app in pod" messages
+const lokiContainerPattern = `(?:\bcontainer(?:\s*[=:]\s*["']?|\s+["'])|\bfailed container\s+)(\w[\w\-]*)`
+
+// Helper function to extract values using regex
+func extractValue(content, pattern string) string {
+	re := regexp.MustCompile(pattern)
+	matches := re.FindStringSubmatch(content)
+	if len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+// Helper function to extract up to max distinct values using regex, in order of first appearance
+func extractValues(content, pattern string, max int) []string {
+	var values []string
+	seen := make(map[string]bool)
+	re := regexp.MustCompile(pattern)
+	for _, matches := range re.FindAllStringSubmatch(content, -1) {
+		if len(values) >= max {
+			break
+		}
+		if len(matches) > 1 && !seen[matches[1]] {
+			seen[matches[1]] = true
+			values = append(values, matches[1])
+		}
+	}
+	return values
+}
+
+// timestampFormats are the timestamp formats recognized in logs, each parsed from its regex match.
+// Formats without a year take it from the reference time: the latest dated timestamp of the log, or now.
+var timestampFormats = []struct {
+	re       *regexp.Regexp
+	yearless bool
+	parse    func(match []string, reference time.Time) (time.Time, error)
+}{
+	// RFC3339 with optional fractional seconds and a Z or numeric offset, e.g. 2006-01-02T15:04:05.000Z or 2006-01-02T17:04:05+02:00
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})`), false, func(match []string, reference time.Time) (time.Time, error) {
+		return time.Parse(time.RFC3339Nano, match[0])
+	}},
+	// Plain date and time, assumed UTC, e.g. 2006-01-02 15:04:05 or 2006-01-02 15:04:05,123
+	{regexp.MustCompile(`(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?:[.,](\d+))?`), false, func(match []string, reference time.Time) (time.Time, error) {
+		t, err := time.Parse("2006-01-02 15:04:05", match[1])
+		if err != nil || match[2] == "" {
+			return t, err
+		}
+		return parseFraction(t, match[2])
+	}},
+	// klog header without a year, e.g. I0102 15:04:05.123456
+	{regexp.MustCompile(`\b[IWEF](\d{4} \d{2}:\d{2}:\d{2})(?:\.(\d+))?\b`), true, func(match []string, reference time.Time) (time.Time, error) {
+		t, err := time.Parse("2006 0102 15:04:05", fmt.Sprintf("%d %s", reference.Year(), match[1]))
+		if err != nil {
+			return t, err
+		}
+		// Use the year closest to the reference, so a log spanning New Year gets both years right,
+		// but never a date in the future
+		for _, candidate := range []time.Time{t.AddDate(-1, 0, 0), t.AddDate(1, 0, 0)} {
+			if candidate.Sub(reference).Abs() < t.Sub(reference).Abs() {
+				t = candidate
+			}
+		}
+		if t.After(time.Now().Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		if match[2] == "" {
+			return t, nil
+		}
+		return parseFraction(t, match[2])
+	}},
+}
+
+// Helper function to return the latest of the times
+func latestTime(times []time.Time) time.Time {
+	latest := times[0]
+	for _, t := range times[1:] {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// Helper function to add fractional seconds given as digits (e.g. "123" or "123456") to a time
+func parseFraction(t time.Time, digits string) (time.Time, error) {
+	if len(digits) > 9 {
+		digits = digits[:9]
+	}
+	nanos, err := strconv.Atoi(digits + strings.Repeat("0", 9-len(digits)))
+	if err != nil {
+		return t, err
+	}
+	return t.Add(time.Duration(nanos)), nil
+}
+
+// Helper function to extract timestamps from the log content
+func extractTimestamps(content string) (time.Time, time.Time) {
+	var timestamps []time.Time
+	reference := time.Now().UTC()
+	for _, format := range timestampFormats {
+		if format.yearless && len(timestamps) > 0 {
+			reference = latestTime(timestamps)
+		}
+		for _, match := range format.re.FindAllStringSubmatch(content, -1) {
+			// Tokens that look like a timestamp but do not parse (e.g. month 13) are skipped
+			if t, err := format.parse(match, reference); err == nil {
+				timestamps = append(timestamps, t.UTC())
+			}
+		}
+	}
+
+	// Logs are often interleaved or newest first, so use the earliest and latest rather than the first and last
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+
+	if len(timestamps) >= 2 {
+		return timestamps[0], timestamps[len(timestamps)-1]
+	} else if len(timestamps) == 1 {
+		return timestamps[0], timestamps[0].Add(5 * time.Minute)
+	} else {
+		return time.Time{}, time.Time{}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportRendersLokiQueriesOnlyInMarkdown(t *testing.T) {
+	queries := []LokiQuery{{URL: "http://loki:3100/loki/api/v1/query_range", Query: `{namespace="shop", pod="web-1"}`, Limit: 1000, Direction: "backward"}}
+
+	for _, pretty := range []bool{false, true} {
+		report := AnalysisReport{LokiQueries: queries, LokiPretty: pretty, Metadata: &RunMetadata{}}
+		want := lokiCommand(queries[0], pretty)
+		if markdown := report.Markdown(); !strings.Contains(markdown, want) {
+			t.Errorf("pretty=%v: Markdown is missing the command %q", pretty, want)
+		}
+	}
+
+	data, err := json.Marshal(AnalysisReport{LokiQueries: queries, Metadata: &RunMetadata{}})
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	var decoded struct {
+		LokiQueries []LokiQuery `json:"loki_queries"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+	if len(decoded.LokiQueries) != 1 || decoded.LokiQueries[0].Query != queries[0].Query || decoded.LokiQueries[0].Limit != 1000 {
+		t.Errorf("loki_queries = %+v, want the typed query", decoded.LokiQueries)
+	}
+}
+
+func TestGenerateLokiQueriesExtractsSelector(t *testing.T) {
+	options := LokiOptions{URL: "http://loki:3100/loki/api/v1/query_range", Limit: 1000, Direction: "backward"}
+
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"structured", `level=error namespace shop pod web-1 container="app" msg="connection refused"`, `{namespace="shop", pod="web-1", container="app"}`},
+		{"quoted after readLog", `Error in namespace shop for pod web-1: container 'app' terminated`, `{namespace="shop", pod="web-1", container="app"}`},
+		{"kubelet", `Back-off restarting failed container app in pod web-1 in namespace shop`, `{namespace="shop", pod="web-1", container="app"}`},
+		{"prose", `Pulling container image nginx:1.25 for pod web-1 in namespace shop`, `{namespace="shop", pod="web-1"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := generateLokiQueries([]string{tt.log}, TimeWindow{}, options)
+			if len(queries) != 1 {
+				t.Fatalf("got %d queries, want 1", len(queries))
+			}
+			if queries[0].Query != tt.want {
+				t.Errorf("query = %s, want %s", queries[0].Query, tt.want)
+			}
+			if encoded := queries[0].Params().Encode(); !strings.Contains(encoded, url.QueryEscape(tt.want)) {
+				t.Errorf("encoded query %s is missing %s", encoded, tt.want)
+			}
+		})
+	}
+}
+
+func TestLokiQueryParamsKeepSubSecondWindow(t *testing.T) {
+	query := LokiQuery{Query: `{pod="web-1"}`, Start: mustTime(t, "2024-01-02T15:04:05.123Z"), End: mustTime(t, "2024-01-02T15:04:09.987654321Z")}
+
+	params := query.Params()
+	if start := params.Get("start"); start != "2024-01-02T15:04:05.123Z" {
+		t.Errorf("start = %s, want 2024-01-02T15:04:05.123Z", start)
+	}
+	if end := params.Get("end"); end != "2024-01-02T15:04:09.987654321Z" {
+		t.Errorf("end = %s, want 2024-01-02T15:04:09.987654321Z", end)
+	}
+}
+
+// Helper function to parse an RFC3339 time in a test
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestTimeWindowSurvivesChunkBoundaries(t *testing.T) {
+	content := "[pod/web-1/app] 2024-01-02T15:00:00Z starting in namespace shop\n" +
+		"[pod/web-1/sidecar] 2024-01-02T15:10:00Z proxy ready\n" +
+		"[pod/web-1/app] 2024-01-02T15:20:00Z panic: nil map\n"
+	window := logTimeWindow(content)
+	wantStart, wantEnd := mustTime(t, "2024-01-02T15:00:00Z"), mustTime(t, "2024-01-02T15:20:00Z")
+	if !window.Start.Equal(wantStart) || !window.End.Equal(wantEnd) {
+		t.Fatalf("window = %v - %v, want %v - %v", window.Start, window.End, wantStart, wantEnd)
+	}
+
+	// One chunk boundary falls exactly before the last timestamp line, the other inside a timestamp
+	boundary := strings.Index(content, "[pod/web-1/app] 2024-01-02T15:20")
+	mid := strings.Index(content, "15:10:00Z") + 3
+	chunkings := [][]string{
+		{content[:boundary], content[boundary:]},
+		{content[:mid], content[mid:]},
+		{splitContainerLogs(content)[0].Content, splitContainerLogs(content)[1].Content},
+	}
+
+	options := LokiOptions{URL: lokiDefaultURL, Limit: 1000, Direction: "backward"}
+	for i, chunks := range chunkings {
+		for j, chunk := range chunks {
+			for _, query := range generateLokiQueries([]string{chunk}, window, options) {
+				if !query.Start.Equal(wantStart) || !query.End.Equal(wantEnd) {
+					t.Errorf("chunking %d, chunk %d: query window = %v - %v, want %v - %v", i, j, query.Start, query.End, wantStart, wantEnd)
+				}
+			}
+		}
+	}
+
+	// The chunk after the boundary alone would only see the last timestamp
+	if start, _ := extractTimestamps(content[boundary:]); start.Equal(wantStart) {
+		t.Errorf("chunk after the boundary unexpectedly covers the full window")
+	}
+}
+
+func TestExtractTimestampsOutOfOrder(t *testing.T) {
+	// Newest-first and interleaved logs still span the earliest to the latest timestamp
+	content := "2024-01-02T15:20:00Z newest\n2024-01-02T15:00:00Z oldest\n2024-01-02T15:10:00Z middle"
+	start, end := extractTimestamps(content)
+	if !start.Equal(mustTime(t, "2024-01-02T15:00:00Z")) || !end.Equal(mustTime(t, "2024-01-02T15:20:00Z")) {
+		t.Errorf("window = %v - %v, want 15:00 - 15:20", start, end)
+	}
+
+	// A single timestamp gets a five minute window
+	start, end = extractTimestamps("2024-01-02T15:00:00Z only line")
+	if !start.Equal(mustTime(t, "2024-01-02T15:00:00Z")) || end.Sub(start) != 5*time.Minute {
+		t.Errorf("window = %v - %v, want 15:00 - 15:05", start, end)
+	}
+
+	if start, end := extractTimestamps("no timestamps here"); !start.IsZero() || !end.IsZero() {
+		t.Errorf("window = %v - %v, want zero times", start, end)
+	}
+}
+
+func TestExtractTimestampsFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantStart string
+		wantEnd   string
+	}{
+		{"RFC3339 with nanoseconds", "2024-01-02T15:04:05.123456789Z a\n2024-01-02T15:04:06Z b", "2024-01-02T15:04:05.123456789Z", "2024-01-02T15:04:06Z"},
+		{"RFC3339 with offsets", "2024-01-02T17:04:05.5+02:00 a\n2024-01-02T10:05:00-05:00 b", "2024-01-02T15:04:05.5Z", "2024-01-02T15:05:00Z"},
+		{"plain with comma fraction", "2024-01-02 15:04:05,250 INFO a\n2024-01-02 15:04:07.5 INFO b", "2024-01-02T15:04:05.25Z", "2024-01-02T15:04:07.5Z"},
+		{"klog takes the year of dated lines", "2023-12-31T23:59:00Z start\nE0101 00:01:02.000001 1 main.go:10] crash", "2023-12-31T23:59:00Z", "2024-01-01T00:01:02.000001Z"},
+		{"unparseable tokens skipped", "2024-13-45T25:00:00Z bogus\n2024-01-02 99:99:99 bogus\n2024-01-02T15:00:00Z a\n2024-01-02T15:01:00Z b", "2024-01-02T15:00:00Z", "2024-01-02T15:01:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := extractTimestamps(tt.content)
+			if !start.Equal(mustTime(t, tt.wantStart)) || !end.Equal(mustTime(t, tt.wantEnd)) {
+				t.Errorf("window = %v - %v, want %s - %s", start, end, tt.wantStart, tt.wantEnd)
+			}
+			if start.Location() != time.UTC || end.Location() != time.UTC {
+				t.Errorf("window = %v - %v, want UTC times", start, end)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lokiRequestInterval is the minimum spacing between requests to Loki, shared by all concurrent queries
+const lokiRequestInterval = 200 * time.Millisecond
+
+// RateLimiter spaces out requests so that at most one starts per interval
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// lokiRateLimiter is shared by every Loki request made during a run
+var lokiRateLimiter = &RateLimiter{interval: lokiRequestInterval}
+
+// Function to block until the next request may start, or until the context is cancelled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(start)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LokiEntry represents a single log line returned by Loki
+type LokiEntry struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+// LokiQueryResult represents the entries returned by one executed query
+type LokiQueryResult struct {
+	Query   string      `json:"query"`
+	Entries []LokiEntry `json:"entries"`
+}
+
+// LokiQueryResponse represents the structure of a query_range response for log streams
+type LokiQueryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"` // [<unix epoch in nanoseconds>, <log line>]
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Function to run a Loki query and return its entries in chronological order.
+// A bearer token is sent when LOKI_TOKEN is set.
+func runLokiQuery(ctx context.Context, query LokiQuery) ([]LokiEntry, error) {
+	if err := lokiRateLimiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("Loki request cancelled: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", query.URL+"?"+query.Params().Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating Loki request: %v", err)
+	}
+	if token := os.Getenv("LOKI_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending Loki request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading Loki response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("Received non-2xx response from Loki: %d\nResponse Body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var response LokiQueryResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("Error parsing Loki response: %v", err)
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("Error: Loki query returned status %q", response.Status)
+	}
+
+	var entries []LokiEntry
+	for _, stream := range response.Data.Result {
+		for _, value := range stream.Values {
+			nanos, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing Loki timestamp %q: %v", value[0], err)
+			}
+			entries = append(entries, LokiEntry{Time: time.Unix(0, nanos).UTC(), Line: value[1]})
+		}
+	}
+
+	// Streams are returned separately, so merge them into one timeline
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// Function to run the Loki queries with at most concurrency requests in flight.
+// Results are returned in the order of the queries; on failure the error of the first failing query is returned.
+func runLokiQueries(ctx context.Context, queries []LokiQuery, concurrency int) ([][]LokiEntry, error) {
+	results := make([][]LokiEntry, len(queries))
+	errs := make([]error, len(queries))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query LokiQuery) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			verbosef("Running Loki query: %s", query.Query)
+			results[i], errs[i] = runLokiQuery(ctx, query)
+		}(i, query)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("Error running Loki query %s: %w", queries[i].Query, err)
+		}
+	}
+	return results, nil
+}
+
+// Function to run the Loki queries and pair each query's LogQL with its entries
+func collectLokiResults(ctx context.Context, queries []LokiQuery, concurrency int) ([]LokiQueryResult, error) {
+	results, err := runLokiQueries(ctx, queries, concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	queryResults := make([]LokiQueryResult, len(queries))
+	for i, query := range queries {
+		queryResults[i] = LokiQueryResult{Query: query.Query, Entries: results[i]}
+	}
+	return queryResults, nil
+}
+
+// Function to render the Loki query results as Markdown, a code block per query
+func renderLokiResults(results []LokiQueryResult) string {
+	if len(results) == 0 {
+		return "No Loki queries were generated for this log.\n"
+	}
+
+	var sb strings.Builder
+	for _, result := range results {
+		sb.WriteString(fmt.Sprintf("## `%s`\n\n", result.Query))
+		if len(result.Entries) == 0 {
+			sb.WriteString("No lines returned.\n\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%d lines returned:\n\n```\n", len(result.Entries)))
+		for _, entry := range result.Entries {
+			sb.WriteString(entry.Time.Format(time.RFC3339Nano) + " " + entry.Line + "\n")
+		}
+		sb.WriteString("```\n\n")
+	}
+	return sb.String()
+}
+
+// Function to fetch the surrounding context of the logs from Loki, truncated to the token budget.
+// It returns an empty string when Loki has no lines for the logs' namespaces and pods.
+func fetchLokiContext(ctx context.Context, logContents []string, window TimeWindow, lokiOptions LokiOptions) (string, error) {
+	results, err := runLokiQueries(ctx, generateLokiQueries(logContents, window, lokiOptions), lokiOptions.Concurrency)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []LokiEntry
+	for _, result := range results {
+		entries = append(entries, result...)
+	}
+
+	// Merge the per-pod results into one timeline
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	var lines []string
+	for _, entry := range entries {
+		lines = append(lines, entry.Time.Format(time.RFC3339Nano)+" "+entry.Line)
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	lines, dropped := truncateToTokenBudget(lines, lokiOptions.ContextTokens)
+	if dropped > 0 {
+		verbosef("Dropped the %d oldest Loki lines to stay within %d tokens", dropped, lokiOptions.ContextTokens)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Surrounding context fetched from Loki (%d lines):\n\n", len(lines)))
+	sb.WriteString(strings.Join(lines, "\n"))
+	sb.WriteString("\n\nUploaded log:\n\n")
+	return sb.String(), nil
+}
+
+// Helper function to keep the most recent lines that fit within the token budget.
+// It returns the kept lines and the number of older lines dropped.
+func truncateToTokenBudget(lines []string, budget int) ([]string, int) {
+	tokens := 0
+	start := len(lines)
+	for start > 0 {
+		lineTokens := estimateTokens(lines[start-1]) + 1 // Count the newline separating the lines
+		if tokens+lineTokens > budget {
+			break
+		}
+		tokens += lineTokens
+		start--
+	}
+	return lines[start:], start
+}
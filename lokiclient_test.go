@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Helper function to serve a fixed query_range response, recording the Authorization header
+func lokiServer(t *testing.T, body string, authorization *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization != nil {
+			*authorization = r.Header.Get("Authorization")
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunLokiQueryMergesStreams(t *testing.T) {
+	t.Setenv("LOKI_TOKEN", "secret")
+	var authorization string
+	server := lokiServer(t, `{"status":"success","data":{"resultType":"streams","result":[`+
+		`{"stream":{"pod":"a"},"values":[["3000000000","third"],["1000000000","first"]]},`+
+		`{"stream":{"pod":"b"},"values":[["2000000000","second"]]}]}}`, &authorization)
+
+	entries, err := runLokiQuery(context.Background(), LokiQuery{URL: server.URL, Query: `{pod="a"}`, Limit: 1000, Direction: "forward"})
+	if err != nil {
+		t.Fatalf("runLokiQuery returned error: %v", err)
+	}
+
+	var lines []string
+	for _, entry := range entries {
+		lines = append(lines, entry.Line)
+	}
+	if len(lines) != 3 || lines[0] != "first" || lines[1] != "second" || lines[2] != "third" {
+		t.Errorf("lines = %v, want [first second third]", lines)
+	}
+	if authorization != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", authorization, "Bearer secret")
+	}
+}
+
+func TestCollectLokiResultsStopsWhenCancelled(t *testing.T) {
+	server := lokiServer(t, `{"status":"success","data":{"result":[]}}`, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := collectLokiResults(ctx, []LokiQuery{{URL: server.URL, Query: `{pod="a"}`}}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want it to wrap context.Canceled", err)
+	}
+	if code := exitCode(err); code != interruptedExitCode {
+		t.Errorf("exitCode = %d, want %d", code, interruptedExitCode)
+	}
+}
+
+func TestLokiFetchAddsCorrelatedLogsSection(t *testing.T) {
+	var opts Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	addLokiFlags(fs, &opts)
+	if err := fs.Parse([]string{"-loki-fetch"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !opts.Loki.Run || opts.Loki.Limit != 1000 {
+		t.Fatalf("-loki-fetch gave Run=%v Limit=%d, want true and 1000", opts.Loki.Run, opts.Loki.Limit)
+	}
+
+	server := lokiServer(t, `{"status":"success","data":{"resultType":"streams","result":[]}}`, nil)
+	queries := []LokiQuery{{URL: server.URL, Query: `{namespace="shop"}`, Limit: opts.Loki.Limit}}
+	results, err := collectLokiResults(context.Background(), queries, 1)
+	if err != nil {
+		t.Fatalf("collectLokiResults returned error: %v", err)
+	}
+
+	markdown := AnalysisReport{LokiResults: results, Metadata: &RunMetadata{}}.Markdown()
+	if !strings.Contains(markdown, "# Correlated Loki Logs") || !strings.Contains(markdown, "No lines returned.") {
+		t.Errorf("Markdown is missing the empty Correlated Loki Logs section:\n%s", markdown)
+	}
+}
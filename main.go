@@ -3,20 +3,16 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
+	"os/signal"
 	"strings"
 	"time"
-
-	"github.com/charmbracelet/glamour"
 )
 
 // Message represents each message in the conversation
@@ -27,9 +23,15 @@ type Message struct {
 
 // RequestBody represents the structure of the API request body
 type RequestBody struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions represents the streaming settings of the request
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // Ask for the usage in a final chunk without choices
 }
 
 // ChatCompletionResponse represents the structure of the API response
@@ -38,6 +40,7 @@ type ChatCompletionResponse struct {
 	Object            string            `json:"object"`
 	Created           int64             `json:"created"`
 	Model             string            `json:"model"`
+	SystemFingerprint string            `json:"system_fingerprint"`
 	Choices           []Choice          `json:"choices"`
 	Usage             Usage             `json:"usage"`
 	GuardrailsResults GuardrailsResults `json:"guardrails_results"`
@@ -45,11 +48,36 @@ type ChatCompletionResponse struct {
 
 // ChatCompletionStreamResponse represents the structure of each stream response chunk
 type ChatCompletionStreamResponse struct {
-	ID      string   `json:"id"`
-	Object  string   `json:"object"`
-	Created int64    `json:"created"`
-	Model   string   `json:"model"`
-	Choices []Choice `json:"choices"`
+	ID                string    `json:"id"`
+	Object            string    `json:"object"`
+	Created           int64     `json:"created"`
+	Model             string    `json:"model"`
+	SystemFingerprint string    `json:"system_fingerprint"`
+	Choices           []Choice  `json:"choices"`
+	Usage             *Usage    `json:"usage,omitempty"` // Only set in the final chunk, with include_usage
+	Error             *APIError `json:"error,omitempty"`
+
+	// Guardrail checks, when the gateway reports them on chunks
+	GuardrailsResults *GuardrailsResults `json:"guardrails_results,omitempty"`
+}
+
+// APIError represents an error reported by the server, e.g. mid-stream as data: {"error": {...}}
+type APIError struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Code    interface{} `json:"code"` // Gateways send either a string or a number
+}
+
+// Function to format the server error for display
+func (e *APIError) Error() string {
+	message := e.Message
+	if e.Type != "" {
+		message = fmt.Sprintf("%s (type: %s)", message, e.Type)
+	}
+	if e.Code != nil {
+		message = fmt.Sprintf("%s (code: %v)", message, e.Code)
+	}
+	return message
 }
 
 // Choice represents each choice in the response
@@ -83,19 +111,103 @@ type Presidio struct {
 	FoundPII bool `json:"found_pii"`
 }
 
+// ChatResult represents the assistant content and server-reported details of a completed request
+type ChatResult struct {
+	Content           string
+	Model             string // Model reported by the server, which may differ from the requested one
+	SystemFingerprint string
+	Usage             Usage
+	Benchmark         *StreamBenchmark   // Set for streamed requests with -benchmark
+	Guardrails        *GuardrailsResults // Set when the gateway reported guardrail checks
+}
+
+// RequestOptions holds the settings used to send a chat completion request
+type RequestOptions struct {
+	URL         string
+	Headers     map[string]string
+	Model       string
+	Stream      bool
+	Delay       time.Duration
+	StrictModel bool // Treat a server-reported model mismatch as an error
+	FailOnPII   bool // Treat a response flagged by the gateway's PII guardrails as an error
+
+	// Ask for confirmation (or abort when not Interactive) above this many estimated prompt tokens; 0 disables the check
+	PromptTokensWarn int
+	AssumeYes        bool
+	Interactive      bool // Set for the chat command, whose user can answer on stdin
+
+	FixMarkdown bool // Repair common Markdown issues in the response before rendering and saving
+
+	RetriesLog string // Append a JSON record of every attempt to this file
+	Retry      RetryPolicy
+
+	// Time limit of each attempt (0 for none): the client timeout for regular requests,
+	// a context deadline for streamed ones so the stream itself is cancelled
+	Timeout time.Duration
+
+	// How often streamed Markdown is re-rendered on a terminal (0 renders only at the end)
+	RenderInterval time.Duration
+	Theme          string // Glamour style of the rendered responses, resolved from -theme
+	Width          int    // Word-wrap width of the rendered responses, from -width or the terminal
+	Raw            bool   // Print the plain Markdown of the responses without rendering
+	StreamRender   bool   // Print the rendered response again once a stream completes
+
+	// Token budget of the log input per request, from -context-percent (0 for no limit)
+	InputTokens int
+
+	// System prompt of the analysis and interactive passes (empty omits the system message)
+	SystemPrompt    string
+	PromptVariables map[string]string // -prompt-var values available to the system prompt template
+
+	// How many more times an interrupted stream is continued by re-prompting (0 saves the partial response instead)
+	Resumes int
+
+	// Save every response body to RecordDir, or read them from ReplayDir instead of calling the API
+	RecordDir string
+	ReplayDir string
+
+	// Measure time to first token, throughput and latency of streamed requests
+	Benchmark bool
+
+	// Custom -redact-config rules applied to every request and to the written outputs
+	Redactions []Redaction
+}
+
+// verbose enables diagnostic output on stderr (set by the -v flag)
+var verbose bool
+
+// quiet suppresses all non-essential output (set by the -quiet flag)
+var quiet bool
+
+// progressOut receives the banners, rendered responses and progress messages.
+// It is discarded with -quiet and moved to stderr when the analysis itself is written to stdout.
+var progressOut io.Writer = os.Stdout
+
+// Helper function to print banners and progress messages to progressOut
+func progressf(format string, args ...interface{}) {
+	fmt.Fprintf(progressOut, format, args...)
+}
+
+// Helper function to print diagnostic messages to stderr when verbose output is enabled
+func verbosef(format string, args ...interface{}) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
 // Function to handle non-streaming response
-func handleNonStreamResponse(body io.Reader) (string, error) {
+func handleNonStreamResponse(body io.Reader, opts RequestOptions) (ChatResult, error) {
 	// Read the response body
 	bodyBytes, err := ioutil.ReadAll(body)
 	if err != nil {
-		return "", fmt.Errorf("Error reading response body: %v", err)
+		return ChatResult{}, fmt.Errorf("Error reading response body: %v", err)
 	}
 
 	// Parse the JSON response
 	var response ChatCompletionResponse
 	err = json.Unmarshal(bodyBytes, &response)
 	if err != nil {
-		return "", fmt.Errorf("Error parsing JSON: %v\nResponse Body: %s\n", err, string(bodyBytes))
+		return ChatResult{}, fmt.Errorf("Error parsing JSON: %v\nResponse Body: %s\n", err, string(bodyBytes))
 	}
 
 	// Extract content
@@ -104,23 +216,50 @@ func handleNonStreamResponse(body io.Reader) (string, error) {
 		assistantResponse.WriteString(choice.Message.Content)
 	}
 
-	// Render the response
-	fmt.Println("\n### Assistant Response ###\n")
-	renderedOutput, err := glamour.Render(assistantResponse.String(), "dark")
-	if err != nil {
-		return "", fmt.Errorf("Error rendering Markdown: %v\n", err)
+	content := applyMarkdownFixes(assistantResponse.String(), opts.FixMarkdown)
+
+	// Render the response, or print the plain Markdown with -raw
+	progressf("\n### Assistant Response ###\n\n")
+	if opts.Raw {
+		progressf("%s\n", content)
+	} else {
+		renderedOutput, err := renderMarkdown(content, opts.Theme, opts.Width)
+		if err != nil {
+			return ChatResult{}, fmt.Errorf("Error rendering Markdown: %v\n", err)
+		}
+		progressf("%s\n", renderedOutput)
 	}
-	fmt.Println(renderedOutput)
 
-	return assistantResponse.String(), nil
+	return ChatResult{
+		Content:           content,
+		Model:             response.Model,
+		SystemFingerprint: response.SystemFingerprint,
+		Usage:             response.Usage,
+		Guardrails:        &response.GuardrailsResults,
+	}, nil
 }
 
 // Function to handle streaming response with delay
-func handleStreamResponse(body io.Reader, delay time.Duration) (string, error) {
+func handleStreamResponse(body io.Reader, opts RequestOptions) (ChatResult, error) {
 	reader := bufio.NewReader(body)
 	var assistantResponse strings.Builder
+	var result ChatResult
 
-	fmt.Println("\n### Assistant Response ###\n")
+	// Count the chunks and time the first token for -benchmark
+	if opts.Benchmark {
+		result.Benchmark = &StreamBenchmark{}
+	}
+
+	progressf("\n### Assistant Response ###\n\n")
+
+	// Re-render the accumulated Markdown every -render-interval on a terminal
+	live := newLiveRenderer(opts)
+	var ticks <-chan time.Time
+	if live != nil {
+		ticker := time.NewTicker(opts.RenderInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
 
 	for {
 		line, err := reader.ReadBytes('\n')
@@ -128,7 +267,8 @@ func handleStreamResponse(body io.Reader, delay time.Duration) (string, error) {
 			if err == io.EOF {
 				break
 			}
-			return "", fmt.Errorf("Error reading response body: %v", err)
+			result.Content = assistantResponse.String()
+			return ChatResult{}, &StreamInterruptedError{Partial: result, Err: fmt.Errorf("Error reading response body: %v", err)}
 		}
 
 		// The stream sends data in the format "data: {...}\n\n"
@@ -146,456 +286,302 @@ func handleStreamResponse(body io.Reader, delay time.Duration) (string, error) {
 			var streamResponse ChatCompletionStreamResponse
 			err = json.Unmarshal(line, &streamResponse)
 			if err != nil {
-				return "", fmt.Errorf("Error parsing JSON: %v\nLine: %s", err, string(line))
+				return ChatResult{}, fmt.Errorf("Error parsing JSON: %v\nLine: %s", err, string(line))
+			}
+
+			// Abort on errors sent as stream events instead of returning truncated output
+			if streamResponse.Error != nil {
+				result.Content = assistantResponse.String()
+				return ChatResult{}, &StreamInterruptedError{Partial: result, Err: fmt.Errorf("Error from server during stream: %v", streamResponse.Error)}
+			}
+
+			// Capture the server-reported model and fingerprint from the chunks
+			if streamResponse.Model != "" {
+				result.Model = streamResponse.Model
+			}
+			if streamResponse.SystemFingerprint != "" {
+				result.SystemFingerprint = streamResponse.SystemFingerprint
+			}
+			if streamResponse.Usage != nil {
+				result.Usage = *streamResponse.Usage
+			}
+			if streamResponse.GuardrailsResults != nil {
+				result.Guardrails = mergeGuardrails(result.Guardrails, *streamResponse.GuardrailsResults)
 			}
 
 			// Append content to assistantResponse
 			for _, choice := range streamResponse.Choices {
 				content := choice.Delta.Content
+				if result.Benchmark != nil && content != "" {
+					if result.Benchmark.firstToken.IsZero() {
+						result.Benchmark.firstToken = time.Now()
+					}
+					result.Benchmark.Chunks++
+				}
 				assistantResponse.WriteString(content)
-				fmt.Print(content)
+				if live != nil {
+					live.Write(content)
+				} else {
+					progressf("%s", content)
+				}
+
+				// Introduce a delay for the typewriter effect (skipped when disabled)
+				if opts.Delay > 0 && !quiet {
+					time.Sleep(opts.Delay)
+				}
+			}
 
-				// Introduce a delay
-				time.Sleep(delay)
+			// Render only between chunks, so the ticker never interrupts a delta
+			select {
+			case <-ticks:
+				if err := live.Render(assistantResponse.String(), opts.FixMarkdown); err != nil {
+					return ChatResult{}, err
+				}
+			default:
 			}
 		}
 	}
 
+	// With live rendering the final render replaces the streamed output in place
+	finalResponse := applyMarkdownFixes(assistantResponse.String(), opts.FixMarkdown)
+	if live != nil {
+		if err := live.Render(assistantResponse.String(), opts.FixMarkdown); err != nil {
+			return ChatResult{}, err
+		}
+		result.Content = finalResponse
+		return result, nil
+	}
+
+	// With -raw or -stream-render=false the streamed Markdown is all there is to show
+	if opts.Raw || !opts.StreamRender {
+		progressf("\n")
+		result.Content = finalResponse
+		return result, nil
+	}
+
 	// After streaming is complete, render the full content with glamour
-	finalResponse := assistantResponse.String()
-	renderedOutput, err := glamour.Render(finalResponse, "dark")
+	renderedOutput, err := renderMarkdown(finalResponse, opts.Theme, opts.Width)
 	if err != nil {
-		return "", fmt.Errorf("Error rendering Markdown: %v\n", err)
+		return ChatResult{}, fmt.Errorf("Error rendering Markdown: %v\n", err)
 	}
 
 	// Optional: Display the rendered output after streaming is complete
-	fmt.Println("\n\n### Formatted Response ###\n")
-	fmt.Println(renderedOutput)
+	progressf("\n\n### Formatted Response ###\n\n")
+	progressf("%s\n", renderedOutput)
 
-	return finalResponse, nil
+	result.Content = finalResponse
+	return result, nil
 }
 
 // Function to send request (streaming or non-streaming)
-func sendRequest(messages []Message, stream bool, headers map[string]string, url string, model string, delay time.Duration) (string, error) {
+func sendRequest(ctx context.Context, messages []Message, opts RequestOptions) (ChatResult, error) {
+	// Keep the custom sensitive identifiers from ever reaching the model
+	messages = redactMessages(messages, opts.Redactions)
+
+	// Guard against accidentally sending a very large (and expensive) prompt; replays cost nothing
+	if opts.PromptTokensWarn > 0 && !opts.AssumeYes && opts.ReplayDir == "" {
+		estimated := estimateMessagesTokens(messages)
+		if estimated > opts.PromptTokensWarn {
+			if !opts.Interactive {
+				return ChatResult{}, fmt.Errorf("Error: prompt is ~%d tokens, above the -prompt-tokens-warn threshold of %d; pass -yes to send it anyway", estimated, opts.PromptTokensWarn)
+			}
+			if !confirm(fmt.Sprintf("This will send ~%d tokens, continue? [y/N] ", estimated)) {
+				return ChatResult{}, fmt.Errorf("Request cancelled: prompt of ~%d tokens was not confirmed", estimated)
+			}
+		}
+	}
+
+	start := time.Now()
 	requestBody := RequestBody{
-		Model:    model,
+		Model:    opts.Model,
 		Messages: messages,
-		Stream:   stream, // Enable or disable streaming
+		Stream:   opts.Stream, // Enable or disable streaming
+	}
+	if opts.Stream {
+		requestBody.StreamOptions = &StreamOptions{IncludeUsage: true}
 	}
 
 	// Marshal the request body to JSON
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("Error marshaling JSON: %v", err)
+		return ChatResult{}, fmt.Errorf("Error marshaling JSON: %v", err)
 	}
 
-	// Create a new HTTP POST request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("Error creating HTTP request: %v", err)
+	// Read the response from a previous run's recording instead of calling the API
+	hash := requestHash(jsonBody)
+	var body io.Reader
+	if opts.ReplayDir != "" {
+		replay, err := openReplay(opts.ReplayDir, hash)
+		if err != nil {
+			return ChatResult{}, err
+		}
+		defer replay.Close()
+		body = replay
+	} else {
+		// Send the request, retrying failures as allowed by the retry policy
+		resp, err := postWithRetries(ctx, opts, jsonBody)
+		if err != nil {
+			return ChatResult{}, err
+		}
+		defer resp.Body.Close()
+		body = resp.Body
 	}
 
-	// Add headers to the request
-	for key, value := range headers {
-		req.Header.Set(key, value)
+	// Record the raw response body for later replay
+	var recorder *ResponseRecorder
+	if opts.RecordDir != "" {
+		recorder, err = recordResponse(opts.RecordDir, hash, body, opts.Redactions)
+		if err != nil {
+			return ChatResult{}, err
+		}
+		body = recorder
 	}
 
-	// Initialize the HTTP client
-	client := &http.Client{
-		Timeout: 0, // No timeout for streaming
+	var result ChatResult
+	if opts.Stream {
+		result, err = handleStreamResponse(body, opts)
+	} else {
+		result, err = handleNonStreamResponse(body, opts)
 	}
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("Error sending HTTP request: %v", err)
+	if recorder != nil {
+		if recordErr := recorder.Finish(err == nil); recordErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", recordErr)
+		}
 	}
-	defer resp.Body.Close()
 
-	// Check for non-2xx status codes
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("Received non-2xx response: %d\nResponse Body: %s\n", resp.StatusCode, string(bodyBytes))
+	// A cancelled request is not resumed
+	if ctx.Err() != nil {
+		return ChatResult{}, fmt.Errorf("Request cancelled: %w", ctx.Err())
 	}
 
-	if stream {
-		// Pass the delay parameter here
-		return handleStreamResponse(resp.Body, delay)
-	} else {
-		return handleNonStreamResponse(resp.Body)
+	// Recover the partial content of an interrupted stream
+	var interrupted *StreamInterruptedError
+	if errors.As(err, &interrupted) {
+		result, err = resumeStream(ctx, messages, opts, interrupted)
 	}
-}
-
-// Function to generate Loki query commands based on the log content
-func generateLokiQueries(logContent string) ([]string, error) {
-	var queries []string
-
-	// Define the Loki gateway URL
-	lokiURL := "https://loki-gatewayK8s.K8s.cloud/loki/api/v1/query_range"
-
-	// Extract relevant information from the log content
-	namespace := extractValue(logContent, `namespace (\w[\w\-]*)`)
-	podName := extractValue(logContent, `pod (\w[\w\-]*)`)
-
-	// Parse timestamps from the log content
-	startTime, endTime := extractTimestamps(logContent)
-
-	// Build the base query parameters
-	params := url.Values{}
-	params.Set("limit", "1000")
-
-	if namespace != "" {
-		params.Set("query", fmt.Sprintf(`{namespace="%s"`, namespace))
-	} else {
-		params.Set("query", `{`)
+	if err != nil {
+		return ChatResult{}, err
 	}
 
-	if podName != "" {
-		params.Set("query", params.Get("query")+fmt.Sprintf(`, pod="%s"`, podName))
+	// Detect gateways silently routing the request to a different model
+	if result.Model != "" && !modelMatches(opts.Model, result.Model) {
+		if opts.StrictModel {
+			return result, fmt.Errorf("Error: requested model %s but the server responded with %s", opts.Model, result.Model)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: requested model %s but the server responded with %s\n", opts.Model, result.Model)
 	}
-
-	params.Set("query", params.Get("query")+"}")
-
-	if !startTime.IsZero() {
-		params.Set("start", startTime.Format(time.RFC3339))
+	if result.SystemFingerprint != "" {
+		verbosef("System fingerprint: %s", result.SystemFingerprint)
 	}
 
-	if !endTime.IsZero() {
-		params.Set("end", endTime.Format(time.RFC3339))
+	// Warn when the gateway's guardrails modified the response or found PII in it
+	if result.Guardrails != nil && result.Guardrails.Flagged() {
+		model := result.Model
+		if model == "" {
+			model = opts.Model
+		}
+		warnGuardrails(model, *result.Guardrails)
+		if opts.FailOnPII {
+			return result, &PIIDetectedError{Model: model, Guardrails: *result.Guardrails}
+		}
 	}
 
-	// Build the full command
-	command := fmt.Sprintf(`curl -G '%s' --data-urlencode '%s'`, lokiURL, params.Encode())
-	queries = append(queries, command)
+	// Report the responsiveness of the stream once it completed
+	if result.Benchmark != nil {
+		result.Benchmark.Finish(start, result.Content)
+		printBenchmark(*result.Benchmark)
+	}
 
-	return queries, nil
+	return result, nil
 }
 
-// Helper function to extract values using regex
-func extractValue(content, pattern string) string {
-	re := regexp.MustCompile(pattern)
-	matches := re.FindStringSubmatch(content)
-	if len(matches) > 1 {
-		return matches[1]
-	}
-	return ""
+// Function to send a background request (e.g. a summary or suggestions) without rendering its response
+func sendSilently(ctx context.Context, messages []Message, opts RequestOptions) (ChatResult, error) {
+	opts.Stream = false
+	out := progressOut
+	progressOut = ioutil.Discard
+	defer func() { progressOut = out }()
+	return sendRequest(ctx, messages, opts)
 }
 
-// Helper function to extract timestamps from the log content
-func extractTimestamps(content string) (time.Time, time.Time) {
-	var timestamps []time.Time
-	re := regexp.MustCompile(`(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z)`)
-	matches := re.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			t, err := time.Parse(time.RFC3339, match[1])
-			if err == nil {
-				timestamps = append(timestamps, t)
-			}
-		}
+// Helper function to append an attempt to the retries log, warning instead of failing the request
+func logRetryRecord(path string, record RetryRecord) {
+	if err := appendRetryRecord(path, record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
+}
 
-	if len(timestamps) >= 2 {
-		return timestamps[0], timestamps[len(timestamps)-1]
-	} else if len(timestamps) == 1 {
-		return timestamps[0], timestamps[0].Add(5 * time.Minute)
-	} else {
-		return time.Time{}, time.Time{}
-	}
+// Helper function to compare the requested and server-reported model names.
+// Dated snapshots of the requested model (e.g. gpt-4o-2024-08-06 for gpt-4o) are treated as a match.
+func modelMatches(requested, reported string) bool {
+	return reported == requested || strings.HasPrefix(reported, requested+"-")
 }
 
 func main() {
-	// Retrieve API keys from environment variables
-	APIKey := os.Getenv("K8s_APIKEY")
-	openAIKey := os.Getenv("OPENAI_API_KEY")
-
-	if APIKey == "" {
-		fmt.Println("Error: K8s_APIKEY environment variable is not set.")
-		return
-	}
-
-	if openAIKey == "" {
-		fmt.Println("Error: OPENAI_API_KEY environment variable is not set.")
-		return
-	}
-
-	// Define the API endpoint
-	url := "https://<.../v1/chat/completions"
-
-	// Create the request headers
-	headers := map[string]string{
-		"Content-Type":   "application/json",
-		"Authorization":  APIKey,
-		"OpenAI-Api-Key": openAIKey,
-	}
-
-	// Define the model
-	model := "gpt-4o"
-
-	// Define command-line flags
-	logPattern := flag.String("log", "", "Partial log filename to match (e.g., '01-LOG')")
-	streamFlag := flag.Bool("stream", false, "Enable streaming output")
-	delayFlag := flag.Int("delay", 10, "Delay in milliseconds between streaming chunks")
-	nonInteractiveFlag := flag.Bool("noninteractive", false, "Enable non-interactive mode")
-	outputFile := flag.String("output", "output.md", "Output Markdown file in non-interactive mode")
+	// Show the usage when no command or flags are given
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	// Dispatch to the requested subcommand
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		name := os.Args[1]
+		for _, command := range commands {
+			if command.Name == name {
+				if err := runCommand(command, os.Args[2:]); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(exitCode(err))
+				}
+				return
+			}
+		}
 
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  -log=\"partial_filename\"\n")
-		fmt.Fprintf(os.Stderr, "        Partial log filename to match (e.g., \"01-LOG\").\n")
-		fmt.Fprintf(os.Stderr, "        The program will search in the LOGS/ directory for files matching this pattern.\n")
-		fmt.Fprintf(os.Stderr, "        If multiple files match, the first one will be processed.\n")
-		fmt.Fprintf(os.Stderr, "  -stream\n")
-		fmt.Fprintf(os.Stderr, "        Enable streaming output.\n")
-		fmt.Fprintf(os.Stderr, "  -delay=milliseconds\n")
-		fmt.Fprintf(os.Stderr, "        Delay in milliseconds between streaming chunks (default 50ms).\n")
-		fmt.Fprintf(os.Stderr, "  -noninteractive\n")
-		fmt.Fprintf(os.Stderr, "        Enable non-interactive mode to perform key point generation and full analysis, then export as Markdown file.\n")
-		fmt.Fprintf(os.Stderr, "  -output=\"filename.md\"\n")
-		fmt.Fprintf(os.Stderr, "        Specify the output Markdown file name (default: output.md).\n")
-		fmt.Fprintf(os.Stderr, "        Example: %s -log=\"01-LOG\" -noninteractive -output=\"analysis.md\"\n", os.Args[0])
+		if name != "help" {
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+		}
+		printUsage()
+		os.Exit(2)
 	}
-	flag.Parse()
 
-	// Check if log pattern is provided
-	if *logPattern == "" {
-		fmt.Println("Please provide a partial log filename using the -log flag.")
-		flag.Usage()
-		return
+	// Fall back to the deprecated flat flags
+	if err := runLegacy(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	// Compute the delay duration
-	delay := time.Duration(*delayFlag) * time.Millisecond
-
-	// Define the log directory
-	logDir := "LOGS/"
-
-	// Create the pattern by appending '*' to the partial filename
-	pattern := *logPattern + "*"
-
-	// Prepend the log directory to the pattern
-	pattern = logDir + pattern
-
-	// Use filepath.Glob to find matching files
-	fileList, err := filepath.Glob(pattern)
-	if err != nil {
-		fmt.Printf("Error finding files with pattern %s: %v\n", pattern, err)
-		return
+// Helper function to map an error to the exit code, keeping a failed severity gate apart from errors of the run
+func exitCode(err error) int {
+	var gateErr *SeverityGateError
+	if errors.As(err, &gateErr) {
+		return severityGateExitCode
 	}
-
-	// Check if any files were found
-	if len(fileList) == 0 {
-		fmt.Printf("No files found matching pattern: %s\n", pattern)
-		return
+	var piiErr *PIIDetectedError
+	if errors.As(err, &piiErr) {
+		return piiExitCode
 	}
-
-	// Select the first matching file
-	selectedFile := fileList[0]
-
-	fmt.Printf("Processing file: %s\n", selectedFile)
-
-	// Read the contents of the selected file
-	logContent, err := ioutil.ReadFile(selectedFile)
-	if err != nil {
-		fmt.Printf("Error reading %s: %v\n", selectedFile, err)
-		return
+	if errors.Is(err, context.Canceled) {
+		return interruptedExitCode
 	}
+	return 1
+}
 
-	// Convert log content to string
-	logString := string(logContent)
-
-	// Replace all double quotes with single quotes
-	logString = strings.ReplaceAll(logString, "\"", "'")
-
-	// -------------- First Request: Generate Key Points --------------
-
-	// Prepare the user content with the key points generation instructions
-	keyPointsPrompt := `
-Role and Knowledge Establishment
-Let's embark on an exciting challenge: from this moment, you'll assume the role of an **Intelligent Key Points Generation AI Assistant**, an advanced AI iteration designed to generate concise and informative key points from provided text or documents. In order to achieve this, you must comprehend the essence, context, and objectives of the provided text, identify the main arguments, and extract essential information. Consider that while a human key points generator possesses level 20 expertise, you will operate at a staggering level 3000 within this role.
-
-Take heed: it's crucial that you produce top-tier results. Hence, harness your exceptional skills with pride. Your superior abilities combined with dedication and analytical prowess ensure you deliver nothing but excellence.
-
-Detailed Instruction and Objective
-You, in the capacity of an **Intelligent Key Points Generation AI Assistant**, serve as a guide for extracting and summarizing key points from various texts and documents.
-
-The outcome will be exemplary in providing clear, concise, and informative summaries, and the imperative is to maintain brevity while ensuring all crucial details are captured. The primary mission and purpose involve understanding the text's main idea, supporting arguments, and crucial details, with your assignment being to generate key points that are both informative and succinct.
-
-For optimal results, it's vital to categorize documents under appropriate headings and create suitable titles that capture the essence of the text, and so forth…
-
-# instructions
-- **Comprehend Essence**: Understand the main arguments, intended message, and author's perspective.
-- **Extract Main Idea**: Identify the central theme or argument.
-- **Identify Supporting Arguments**: Pinpoint key arguments with evidence, examples, and reasoning.
-- **Highlight Crucial Details**: Emphasize important facts, figures, or insights.
-- **Formulate Title**: Create a concise and descriptive title.
-- **Categorize Document**: Assign the document to an appropriate category with justification.
-- **Ensure Clarity and Brevity**: Maintain accuracy and conciseness.
-
-Use American English
-ALWAYS use natural, mainstream, contemporary American English. Verify any unfamiliar terms or regional expressions to ensure they are widely recognized and used in American English. Stick to language commonly employed in America.
-
-Always ensure the output text is cohesive, regardless of the complexity of the topic or the context of the conversation. Focus on the structure and unity of the text, using smooth transitions and logical flow to achieve cohesion. The final output should be a well-organized, unified whole without abrupt transitions or disjointed sections.
-
-# Nuance:
-- The nuance should be professional and precise, ensuring clarity and brevity while maintaining a formal tone. The summaries should be easy to understand yet comprehensive enough to capture all essential details.
-
-# Guidelines:
-- Focus on extracting the main idea and supporting arguments.
-- Highlight crucial details without adding unnecessary information.
-- Ensure the summaries are clear, concise, and informative.
-- Use markdown or other formatting tools to emphasize key points.
-- Continuously improve based on feedback to enhance clarity and usefulness.
-
-# Structure:
-Ensure your response adheres to a specific format. Random placements are not permitted. This format dictates how each of your messages should appear. Adhere to this format:
-**Main Idea**: - (Provide the central theme or argument.);
-**Supporting Arguments**: - (List key arguments with evidence, examples, and reasoning.);
-**Crucial Details**: - (Highlight important facts, figures, or insights.);
-**Title**: - (Create a concise and descriptive title.);
-**Category**: - (Assign the document to an appropriate category with justification.);
-
-Thoroughly review the <context> and to fully grasp its background, details, and relevance to the task and carefully justify the response in the format:
-<justify>
-  Justification for the response.
-</justify>
-`
-
-	// Combine the key points prompt with the log content
-	userContentFirst := fmt.Sprintf("%s\n<context>\n%s\n</context>", keyPointsPrompt, logString)
+// interruptedExitCode is the conventional exit code of a program stopped by Ctrl+C (128 + SIGINT)
+const interruptedExitCode = 130
 
-	// First request messages (no system prompt)
-	messagesFirst := []Message{
-		{
-			Role:    "user",
-			Content: userContentFirst,
-		},
+// Function to create the context of a command, cancelled by Ctrl+C so the current request is aborted
+// and the command exits cleanly. Interactive commands get a plain context instead: they catch Ctrl+C
+// per turn with turnContext, so it cancels the current response and the session goes on.
+func interruptContext(interactive bool) (context.Context, context.CancelFunc) {
+	if interactive {
+		return context.WithCancel(context.Background())
 	}
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
 
-	// Send the first request
-	assistantResponseFirst, err := sendRequest(messagesFirst, *streamFlag, headers, url, model, delay)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	if *nonInteractiveFlag {
-		// -------------- Non-Interactive Mode: Perform Full Analysis --------------
-
-		// Set the system prompt for the analysis
-		systemPrompt := `You are an expert Kubernetes administrator and DevOps engineer. Your primary role is to analyze and troubleshoot Kubernetes pod logs, identify issues such as pod crashes, OOMKilled errors, and other deployment problems, and provide actionable solutions and best practices to resolve them.
-
-When responding:
-- Provide structured output using markdown tables, bullet points, or JSON where appropriate.
-- Include step-by-step reasoning and detailed explanations for each troubleshooting step.
-- Highlight key actions and recommendations.
-- Ensure clarity and comprehensiveness to address complex Kubernetes issues effectively.`
-
-		// Prepare the analysis messages
-		analysisMessages := []Message{
-			{
-				Role:    "system",
-				Content: systemPrompt,
-			},
-			{
-				Role:    "user",
-				Content: "Here are the key points from the log analysis:\n\n" + assistantResponseFirst,
-			},
-		}
-
-		// Send the analysis request
-		analysisResponse, err := sendRequest(analysisMessages, *streamFlag, headers, url, model, delay)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-
-		// Combine key points and analysis
-		var outputBuilder strings.Builder
-		outputBuilder.WriteString("# Key Points\n\n")
-		outputBuilder.WriteString(assistantResponseFirst)
-		outputBuilder.WriteString("\n\n# Analysis and Recommendations\n\n")
-		outputBuilder.WriteString(analysisResponse)
-
-		// Generate Loki query commands
-		lokiQueries, err := generateLokiQueries(logString)
-		if err != nil {
-			fmt.Printf("Error generating Loki queries: %v\n", err)
-			return
-		}
-
-		// Add Loki queries to the output
-		outputBuilder.WriteString("\n\n# Loki Query Commands\n\n")
-		for _, query := range lokiQueries {
-			outputBuilder.WriteString(fmt.Sprintf("```\n%s\n```\n\n", query))
-		}
-
-		// Save to output file
-		err = ioutil.WriteFile(*outputFile, []byte(outputBuilder.String()), 0644)
-		if err != nil {
-			fmt.Printf("Error writing to file %s: %v\n", *outputFile, err)
-			return
-		}
-
-		fmt.Printf("\nAnalysis saved to %s\n", *outputFile)
-	} else {
-		// -------------- Interactive Mode --------------
-
-		// Set the system prompt for the interactive session
-		systemPrompt := `You are an expert Kubernetes administrator and DevOps engineer. Your primary role is to analyze and troubleshoot Kubernetes pod logs, identify issues such as pod crashes, OOMKilled errors, and other deployment problems, and provide actionable solutions and best practices to resolve them.
-
-When responding:
-- Provide structured output using markdown tables, bullet points, or JSON where appropriate.
-- Include step-by-step reasoning and detailed explanations for each troubleshooting step.
-- Highlight key actions and recommendations.
-- Ensure clarity and comprehensiveness to address complex Kubernetes issues effectively.`
-
-		// Initialize messages for interactive session
-		messages := []Message{
-			{
-				Role:    "system",
-				Content: systemPrompt,
-			},
-			{
-				Role:    "user",
-				Content: "Here are the key points from the log analysis:\n\n" + assistantResponseFirst,
-			},
-		}
-
-		// Start interactive chat session
-		scanner := bufio.NewScanner(os.Stdin)
-		fmt.Println("\nEnter your message (type 'exit' to quit):")
-		for {
-			fmt.Print("> ")
-			if !scanner.Scan() {
-				break
-			}
-			userInput := scanner.Text()
-
-			// Check for exit command
-			if strings.ToLower(strings.TrimSpace(userInput)) == "exit" {
-				fmt.Println("Exiting chat session.")
-				break
-			}
-
-			// Append user's message to messages
-			messages = append(messages, Message{
-				Role:    "user",
-				Content: userInput,
-			})
-
-			// Send request with updated messages
-			assistantResponse, err := sendRequest(messages, *streamFlag, headers, url, model, delay)
-			if err != nil {
-				fmt.Println(err)
-				break
-			}
-
-			// Append assistant's response to messages
-			messages = append(messages, Message{
-				Role:    "assistant",
-				Content: assistantResponse,
-			})
-		}
-	}
+// Function to create the context of an interactive turn, cancelled by Ctrl+C until stop is called.
+// Once stopped, Ctrl+C at the prompt ends the program as usual.
+func turnContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
 }
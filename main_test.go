@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// Helper function to discard the progress output of a test
+func discardProgress(t *testing.T) {
+	t.Helper()
+	original := progressOut
+	progressOut = ioutil.Discard
+	t.Cleanup(func() { progressOut = original })
+}
+
+func TestHandleStreamResponseAbortsOnErrorChunk(t *testing.T) {
+	discardProgress(t)
+	body := `data: {"id":"1","model":"gpt-4o","choices":[{"delta":{"content":"The pod "}}]}
+
+data: {"id":"1","choices":[{"delta":{"content":"crashed"}}]}
+
+data: {"error":{"message":"Rate limit reached","type":"rate_limit_error","code":429}}
+
+data: {"id":"1","choices":[{"delta":{"content":" because"}}]}
+
+data: [DONE]
+`
+
+	_, err := handleStreamResponse(strings.NewReader(body), RequestOptions{})
+	var interrupted *StreamInterruptedError
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("err = %v, want a StreamInterruptedError", err)
+	}
+	if !strings.Contains(err.Error(), "Rate limit reached (type: rate_limit_error) (code: 429)") {
+		t.Errorf("err = %q, want the server error message, type and code", err)
+	}
+	if interrupted.Partial.Content != "The pod crashed" {
+		t.Errorf("partial content = %q, want %q", interrupted.Partial.Content, "The pod crashed")
+	}
+}
+
+func TestHandleStreamResponseCompletes(t *testing.T) {
+	discardProgress(t)
+	body := "data: {\"model\":\"gpt-4o-2024-08-06\",\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n"
+
+	result, err := handleStreamResponse(strings.NewReader(body), RequestOptions{})
+	if err != nil {
+		t.Fatalf("handleStreamResponse returned error: %v", err)
+	}
+	if result.Content != "ok" || result.Model != "gpt-4o-2024-08-06" {
+		t.Errorf("result = %+v, want content ok from gpt-4o-2024-08-06", result)
+	}
+}
+
+func TestSendRequestAbortsLargePromptWhenNotInteractive(t *testing.T) {
+	discardProgress(t)
+	server, sent := chatServer(t, 0)
+	messages := []Message{{Role: "user", Content: strings.Repeat("error ", 400)}}
+
+	// An analyze run never waits for an answer, even on a terminal
+	options := RequestOptions{URL: server.URL, Raw: true, PromptTokensWarn: 100}
+	_, err := sendRequest(context.Background(), messages, options)
+	if err == nil || !strings.Contains(err.Error(), "pass -yes to send it anyway") {
+		t.Fatalf("err = %v, want the -prompt-tokens-warn abort", err)
+	}
+	if len(*sent) != 0 {
+		t.Errorf("%d requests sent, want none", len(*sent))
+	}
+
+	options.AssumeYes = true
+	if _, err := sendRequest(context.Background(), messages, options); err != nil {
+		t.Fatalf("sendRequest with -yes returned error: %v", err)
+	}
+	if len(*sent) != 1 {
+		t.Errorf("%d requests sent with -yes, want 1", len(*sent))
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Opening or closing code fence (``` or ~~~, optionally followed by a language)
+	codeFenceRegex = regexp.MustCompile("^\\s*(```|~~~)")
+
+	// Table header separator row, e.g. "|---|:---:|"
+	tableSeparatorRegex = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+)
+
+// Function to repair common Markdown issues in model output before rendering and saving.
+// It returns the repaired Markdown and a description of each fix applied.
+func fixMarkdown(markdown string) (string, []string) {
+	var fixes []string
+	lines := strings.Split(markdown, "\n")
+	var out []string
+
+	inFence := false
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// Track code fences so tables inside code blocks are left alone
+		if matches := codeFenceRegex.FindStringSubmatch(line); matches != nil {
+			if !inFence {
+				inFence, fence = true, matches[1]
+			} else if matches[1] == fence {
+				inFence = false
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if inFence || !isTableRow(line) {
+			out = append(out, line)
+			continue
+		}
+
+		// Collect the contiguous table block
+		start := i
+		for i < len(lines) && isTableRow(lines[i]) {
+			i++
+		}
+		block := lines[start:i]
+		i--
+
+		// A table needs a blank line before it to be recognized
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+			fixes = append(fixes, fmt.Sprintf("added a blank line before the table at line %d", start+1))
+		}
+
+		fixedBlock, blockFixes := fixTable(block, start+1)
+		out = append(out, fixedBlock...)
+		fixes = append(fixes, blockFixes...)
+	}
+
+	// Close a code fence left open at the end of the response
+	if inFence {
+		out = append(out, fence)
+		fixes = append(fixes, "closed an unterminated code fence")
+	}
+
+	return strings.Join(out, "\n"), fixes
+}
+
+// Helper function to check whether a line is a Markdown table row
+func isTableRow(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "|") && strings.Count(trimmed, "|") >= 2
+}
+
+// Helper function to split a table row into its cells
+func tableCells(line string) []string {
+	trimmed := strings.TrimSpace(line)
+	trimmed = strings.TrimPrefix(trimmed, "|")
+	trimmed = strings.TrimSuffix(trimmed, "|")
+
+	// Keep escaped pipes inside their cell
+	trimmed = strings.ReplaceAll(trimmed, `\|`, "\x00")
+	cells := strings.Split(trimmed, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(strings.ReplaceAll(cell, "\x00", `\|`))
+	}
+	return cells
+}
+
+// Function to make every row of a table match the header's column count,
+// inserting the header separator row if it is missing
+func fixTable(block []string, firstLine int) ([]string, []string) {
+	var fixes []string
+	columns := len(tableCells(block[0]))
+
+	var rows []string
+	rows = append(rows, block[0])
+
+	// Line number of the first body row
+	bodyLine := firstLine + 1
+
+	rest := block[1:]
+	separator := "|" + strings.Repeat(" --- |", columns)
+	if len(rest) == 0 || !tableSeparatorRegex.MatchString(rest[0]) {
+		fixes = append(fixes, fmt.Sprintf("added a missing header separator to the table at line %d", firstLine))
+		rows = append(rows, separator)
+	} else {
+		if len(tableCells(rest[0])) == columns {
+			rows = append(rows, rest[0])
+		} else {
+			fixes = append(fixes, fmt.Sprintf("rebuilt the header separator of the table at line %d", firstLine))
+			rows = append(rows, separator)
+		}
+		rest = rest[1:]
+		bodyLine++
+	}
+
+	for j, row := range rest {
+		cells := tableCells(row)
+		switch {
+		case len(cells) < columns:
+			cells = append(cells, make([]string, columns-len(cells))...)
+			fixes = append(fixes, fmt.Sprintf("padded a row with missing columns in the table at line %d", bodyLine+j))
+		case len(cells) > columns:
+			// Merge the overflow into the last column rather than dropping content
+			merged := strings.Join(cells[columns-1:], " / ")
+			cells = append(cells[:columns-1], merged)
+			fixes = append(fixes, fmt.Sprintf("merged extra columns of a row in the table at line %d", bodyLine+j))
+		default:
+			rows = append(rows, row)
+			continue
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+	}
+
+	return rows, fixes
+}
+
+// Helper function to apply the Markdown fixes when enabled, reporting each fix under -v
+func applyMarkdownFixes(content string, enabled bool) string {
+	if !enabled {
+		return content
+	}
+
+	fixed, fixes := fixMarkdown(content)
+	for _, fix := range fixes {
+		verbosef("Markdown fix: %s", fix)
+	}
+	return fixed
+}
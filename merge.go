@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding"
+)
+
+// severityRanks orders the analysis severities, most severe first
+var severityRanks = map[string]int{"critical": 5, "high": 4, "medium": 3, "low": 2, "info": 1}
+
+// levelSeverities maps the SARIF level of a local finding to an analysis severity
+var levelSeverities = map[string]string{"error": "high", "warning": "medium", "note": "low"}
+
+// Function to determine the severity of a report: the model's severity with -analyze-json,
+// otherwise the most severe level of the local findings ("info" when nothing was found)
+func reportSeverity(report AnalysisReport) string {
+	if report.Structured != nil && report.Structured.Severity != "" {
+		return report.Structured.Severity
+	}
+
+	severity := "info"
+	for _, finding := range report.Findings {
+		level := "warning"
+		if rule, ok := findingRules[finding.Title]; ok {
+			level = rule.DefaultConfiguration.Level
+		}
+		if candidate := levelSeverities[level]; severityRanks[candidate] > severityRanks[severity] {
+			severity = candidate
+		}
+	}
+	return severity
+}
+
+// Function to combine the reports of a batch into one Markdown document, with a table of contents
+// and a section per log, sorted by severity (most severe first) and then by log file
+func mergeReports(reports []AnalysisReport) string {
+	sorted := append([]AnalysisReport{}, reports...)
+	severities := make(map[string]string)
+	for _, report := range sorted {
+		severities[report.LogFile] = reportSeverity(report)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := severityRanks[severities[sorted[i].LogFile]], severityRanks[severities[sorted[j].LogFile]]
+		if ri != rj {
+			return ri > rj
+		}
+		return sorted[i].LogFile < sorted[j].LogFile
+	})
+
+	var sb strings.Builder
+	sb.WriteString("# Merged Analysis\n\n## Contents\n\n")
+	sb.WriteString("| # | Log | Severity | Findings |\n")
+	sb.WriteString("|---|-----|----------|----------|\n")
+	for i, report := range sorted {
+		var findings []string
+		for _, finding := range report.Findings {
+			findings = append(findings, finding.Title)
+		}
+		sb.WriteString(fmt.Sprintf("| %d | [%s](#log-%d) | %s | %s |\n",
+			i+1, tableCell(filepath.Base(report.LogFile), 80), i+1, severities[report.LogFile], valueOrDash(strings.Join(findings, ", "))))
+	}
+
+	// Each report is nested one heading level below its file section
+	for i, report := range sorted {
+		sb.WriteString(fmt.Sprintf("\n<a id=\"log-%d\"></a>\n\n# %d. %s (%s)\n\n", i+1, i+1, report.LogFile, severities[report.LogFile]))
+		sb.WriteString(demoteHeadings(report.Markdown()))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Helper function to demote every Markdown heading by one level, leaving code blocks untouched
+func demoteHeadings(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(line, "#") {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Function to write the merged report of a batch
+func writeMergedReport(reports []AnalysisReport, path string, enc encoding.Encoding, redactions []Redaction, strip bool) error {
+	merged := redactText(mergeReports(reports), redactions)
+	if strip {
+		merged = stripANSI(merged)
+	}
+	data, err := encodeOutput([]byte(merged), enc)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("Error writing to file %s: %v", path, err)
+	}
+	progressf("Merged analysis saved to %s\n", path)
+	return nil
+}
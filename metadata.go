@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PassMetadata records what was requested and what the server reported for a single request
+type PassMetadata struct {
+	Name              string  `json:"name"`
+	RequestedModel    string  `json:"requested_model"`
+	ResponseModel     string  `json:"response_model"`
+	SystemFingerprint string  `json:"system_fingerprint"`
+	Usage             Usage   `json:"usage"`
+	Cost              float64 `json:"cost,omitempty"` // Estimated cost in dollars, when pricing is configured
+	Priced            bool    `json:"priced"`
+
+	// Responsiveness of the streamed request, set with -benchmark
+	Benchmark *StreamBenchmark `json:"benchmark,omitempty"`
+}
+
+// RunMetadata collects metadata for every request made during a run
+type RunMetadata struct {
+	Passes []PassMetadata `json:"passes"`
+
+	// Dominant language of the log messages, set with -detect-language
+	Language *LanguageInfo `json:"language,omitempty"`
+
+	// Estimated tokens of each prompt component, set with -token-breakdown
+	TokenBreakdown []TokenComponent `json:"token_breakdown,omitempty"`
+
+	// When set, every recorded response is also retained as raw Markdown
+	Raw *RawRecorder `json:"-"`
+
+	// When set, the cost of every recorded request is estimated
+	Pricing Pricing `json:"-"`
+
+	// Usage and cost accumulated across the whole run, shared between forks
+	Totals *UsageTotals `json:"-"`
+
+	// When set, the usage of every recorded request is printed, set with -show-usage
+	ShowUsage bool `json:"-"`
+}
+
+// Function to create the run metadata from the flags, opening the raw response file and pricing
+func newRunMetadata(opts *Options, redactions []Redaction) (*RunMetadata, error) {
+	raw, err := openRawRecorder(opts.RetainRaw, redactions)
+	if err != nil {
+		return nil, err
+	}
+
+	pricing, err := loadPricing(opts.PricingFile, ModelPricing{Prompt: opts.PricePerKPrompt, Completion: opts.PricePerKCompletion})
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+
+	return &RunMetadata{Raw: raw, Pricing: pricing, Totals: &UsageTotals{}, ShowUsage: opts.ShowUsage}, nil
+}
+
+// Function to start an empty metadata record (e.g. for another log in a batch)
+// that shares the raw recorder, pricing and totals of the run
+func (m *RunMetadata) Fork() *RunMetadata {
+	return &RunMetadata{Raw: m.Raw, Pricing: m.Pricing, Totals: m.Totals, ShowUsage: m.ShowUsage}
+}
+
+// Function to release the resources held by the run metadata
+func (m *RunMetadata) Close() error {
+	return m.Raw.Close()
+}
+
+// Function to record the metadata of a completed request
+func (m *RunMetadata) Record(name, requestedModel string, result ChatResult) {
+	pass := PassMetadata{
+		Name:              name,
+		RequestedModel:    requestedModel,
+		ResponseModel:     result.Model,
+		SystemFingerprint: result.SystemFingerprint,
+		Usage:             result.Usage,
+		Benchmark:         result.Benchmark,
+	}
+
+	// Price the request by the model that actually served it
+	if m.Pricing != nil {
+		model := result.Model
+		if model == "" {
+			model = requestedModel
+		}
+		pass.Cost, pass.Priced = m.Pricing.Cost(model, result.Usage)
+		if !pass.Priced {
+			verbosef("No price configured for model %s", model)
+		}
+	}
+	m.Passes = append(m.Passes, pass)
+
+	if m.Totals != nil {
+		m.Totals.Add(pass.Usage, pass.Cost)
+		if pass.Benchmark != nil {
+			m.Totals.Benchmarks.Add(*pass.Benchmark)
+		}
+	}
+
+	// Usage is a diagnostic, so it goes to stderr like the cost summary
+	if m.ShowUsage {
+		fmt.Fprint(os.Stderr, "\n"+renderPassUsage(pass, m.Pricing != nil))
+	}
+
+	if err := m.Raw.Save(name, result.Content); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// Function to render the run metadata as a Markdown table
+func renderMetadata(m RunMetadata) string {
+	var sb strings.Builder
+	sb.WriteString("| Pass | Requested Model | Response Model | System Fingerprint | Prompt Tokens | Completion Tokens |")
+	if m.Pricing != nil {
+		sb.WriteString(" Estimated Cost |")
+	}
+	sb.WriteString("\n|------|-----------------|----------------|--------------------|---------------|-------------------|")
+	if m.Pricing != nil {
+		sb.WriteString("----------------|")
+	}
+	sb.WriteString("\n")
+
+	var totalCost float64
+	for _, pass := range m.Passes {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %d | %d |",
+			pass.Name, pass.RequestedModel, valueOrDash(pass.ResponseModel), valueOrDash(pass.SystemFingerprint),
+			pass.Usage.PromptTokens, pass.Usage.CompletionTokens))
+		if m.Pricing != nil {
+			if pass.Priced {
+				sb.WriteString(fmt.Sprintf(" $%.4f |", pass.Cost))
+			} else {
+				sb.WriteString(" - |")
+			}
+		}
+		sb.WriteString("\n")
+		totalCost += pass.Cost
+	}
+
+	if m.Pricing != nil {
+		sb.WriteString(fmt.Sprintf("\n**Estimated total cost**: $%.4f\n", totalCost))
+	}
+	if m.Language != nil {
+		sb.WriteString(fmt.Sprintf("\n**Detected language**: %s (%s, confidence %.2f)\n", m.Language.Name, m.Language.Code, m.Language.Confidence))
+	}
+	if len(m.TokenBreakdown) > 0 {
+		sb.WriteString("\n**Estimated prompt tokens by component**:\n\n")
+		sb.WriteString(renderTokenBreakdown(m.TokenBreakdown))
+	}
+	return sb.String()
+}
+
+// Helper function to display a placeholder for values the server did not report
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// NetworkIssue represents a group of identical network errors against the same target
+type NetworkIssue struct {
+	Kind    string // dns, connection refused, timeout, deadline exceeded, connection reset
+	Target  string // host or host:port, when present in the error
+	Count   int
+	Example string
+}
+
+// networkErrorKinds maps network error messages from Go, Java and Python to a kind
+var networkErrorKinds = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"dns", regexp.MustCompile(`(?i)no such host|UnknownHostException|Name or service not known|Temporary failure in name resolution|nodename nor servname provided|getaddrinfo ENOTFOUND`)},
+	{"connection refused", regexp.MustCompile(`(?i)connection refused|ECONNREFUSED`)},
+	{"connection reset", regexp.MustCompile(`(?i)connection reset by peer|ECONNRESET|broken pipe`)},
+	{"timeout", regexp.MustCompile(`(?i)i/o timeout|SocketTimeoutException|connect timed out|Read timed out|ETIMEDOUT|ConnectTimeoutError|ReadTimeoutError`)},
+	{"deadline exceeded", regexp.MustCompile(`(?i)context deadline exceeded|DEADLINE_EXCEEDED`)},
+}
+
+// networkTargetPatterns extract the host or host:port from a network error, in order of preference
+var networkTargetPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`dial (?:tcp|udp)[46]? ([\w.\-\[\]:]+:\d+)`),
+	regexp.MustCompile(`lookup ([\w.\-]+)`),
+	regexp.MustCompile(`host=['"]([\w.\-]+)['"], port=(\d+)`),
+	regexp.MustCompile(`UnknownHostException: ([\w.\-]+)`),
+	regexp.MustCompile(`(?:ENOTFOUND|ECONNREFUSED|ETIMEDOUT) ([\w.\-:]+)`),
+	regexp.MustCompile(`['"]?(https?://[^\s'"]+)`),
+}
+
+// Function to extract network errors from the log content, grouped by kind and target
+func extractNetworkIssues(content string) []NetworkIssue {
+	var issues []NetworkIssue
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		kind := ""
+		for _, errorKind := range networkErrorKinds {
+			if errorKind.re.MatchString(line) {
+				kind = errorKind.kind
+				break
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		target := extractNetworkTarget(line)
+		key := kind + "|" + target
+		if i, ok := index[key]; ok {
+			issues[i].Count++
+			continue
+		}
+
+		index[key] = len(issues)
+		issues = append(issues, NetworkIssue{Kind: kind, Target: target, Count: 1, Example: line})
+	}
+
+	return issues
+}
+
+// Helper function to extract the target host or host:port of a network error
+func extractNetworkTarget(line string) string {
+	for _, re := range networkTargetPatterns {
+		matches := re.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		// Python reports host and port separately
+		if len(matches) > 2 && matches[2] != "" {
+			return matches[1] + ":" + matches[2]
+		}
+
+		// Reduce URLs to their host
+		if u, err := url.Parse(matches[1]); err == nil && u.Host != "" {
+			return u.Host
+		}
+		return matches[1]
+	}
+	return ""
+}
+
+// Function to render the network issues as a Markdown table
+func renderNetworkIssues(issues []NetworkIssue) string {
+	var sb strings.Builder
+	sb.WriteString("| Kind | Target | Occurrences | Example |\n")
+	sb.WriteString("|------|--------|-------------|---------|\n")
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | `%s` |\n",
+			issue.Kind, valueOrDash(issue.Target), issue.Count, tableCell(issue.Example, 160)))
+	}
+	return sb.String()
+}
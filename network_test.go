@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractNetworkIssues(t *testing.T) {
+	lines := []string{
+		`dial tcp 10.0.0.5:5432: connect: connection refused`,
+		`E0102 failed to sync: dial tcp: lookup redis.shop.svc.cluster.local on 10.96.0.10:53: no such host`,
+		`dial tcp 10.0.0.5:5432: connect: connection refused`,
+		`java.net.UnknownHostException: payments.internal`,
+		`java.net.SocketTimeoutException: Read timed out`,
+		`requests.exceptions.ConnectTimeout: HTTPSConnectionPool(host='api.example.com', port=443): Max retries exceeded with url: /v1 (Caused by ConnectTimeoutError(...))`,
+		`rpc error: code = DeadlineExceeded desc = context deadline exceeded`,
+		`read tcp 10.0.0.7:41234->10.0.0.9:8080: read: connection reset by peer`,
+		`INFO request completed in 12ms`,
+	}
+	content := ""
+	for _, line := range lines {
+		content += line + "\n"
+	}
+
+	issues := extractNetworkIssues(content)
+	for i := range issues {
+		issues[i].Example = "" // Only the grouping is checked here
+	}
+	want := []NetworkIssue{
+		{Kind: "connection refused", Target: "10.0.0.5:5432", Count: 2},
+		{Kind: "dns", Target: "redis.shop.svc.cluster.local", Count: 1},
+		{Kind: "dns", Target: "payments.internal", Count: 1},
+		{Kind: "timeout", Count: 1},
+		{Kind: "timeout", Target: "api.example.com:443", Count: 1},
+		{Kind: "deadline exceeded", Count: 1},
+		{Kind: "connection reset", Count: 1},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("extractNetworkIssues =\n%+v\nwant\n%+v", issues, want)
+	}
+}
+
+func TestExtractNetworkTargetReducesURLsToHost(t *testing.T) {
+	line := `Get "https://billing.example.com:8443/v1/invoices": dial tcp: i/o timeout`
+	if target := extractNetworkTarget(line); target != "billing.example.com:8443" {
+		t.Errorf("extractNetworkTarget = %q, want %q", target, "billing.example.com:8443")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Escape sequences a terminal wraps pasted text in once bracketed paste mode is enabled
+const (
+	bracketedPasteEnable  = "\x1b[?2004h"
+	bracketedPasteDisable = "\x1b[?2004l"
+	bracketedPasteStart   = "\x1b[200~"
+	bracketedPasteEnd     = "\x1b[201~"
+)
+
+// maxInputLine is the longest line accepted from the REPL, large enough for pasted log lines
+const maxInputLine = 1024 * 1024
+
+// Function to read the user's messages from the input, one per line.
+// Lines wrapped in bracketed paste markers, or arriving within the debounce interval of each
+// other (faster than anyone types), are batched into a single multi-line message.
+// The channel is closed when the input ends.
+func readUserMessages(input io.Reader, debounce time.Duration) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxInputLine)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		for line := range lines {
+			batch := []string{line}
+			open := true
+
+			if strings.Contains(line, bracketedPasteStart) {
+				// Collect the whole paste, however slowly it arrives
+				for !strings.Contains(batch[len(batch)-1], bracketedPasteEnd) {
+					next, ok := <-lines
+					if !ok {
+						open = false
+						break
+					}
+					batch = append(batch, next)
+				}
+			} else if debounce > 0 {
+				// Keep collecting while lines arrive faster than human typing
+			collect:
+				for {
+					select {
+					case next, ok := <-lines:
+						if !ok {
+							open = false
+							break collect
+						}
+						batch = append(batch, next)
+					case <-time.After(debounce):
+						break collect
+					}
+				}
+			}
+
+			message := strings.Join(batch, "\n")
+			message = strings.ReplaceAll(message, bracketedPasteStart, "")
+			message = strings.ReplaceAll(message, bracketedPasteEnd, "")
+			if len(batch) > 1 {
+				verbosef("Batched %d pasted lines into one message", len(batch))
+			}
+			messages <- message
+
+			if !open {
+				return
+			}
+		}
+	}()
+	return messages
+}
+
+// Function to enable bracketed paste mode when the REPL runs in a terminal.
+// It returns a function restoring the terminal, to be deferred.
+func enableBracketedPaste() func() {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return func() {}
+	}
+	os.Stdout.WriteString(bracketedPasteEnable)
+	return func() { os.Stdout.WriteString(bracketedPasteDisable) }
+}
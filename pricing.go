@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ModelPricing is the price in dollars per 1K tokens for a model
+type ModelPricing struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// Pricing maps model names to their per-1K token prices, loaded from the -pricing file, e.g.
+//
+//	{"gpt-4o": {"prompt": 0.0025, "completion": 0.01}}
+type Pricing map[string]ModelPricing
+
+// defaultPricingKey holds the -price-per-1k-prompt and -price-per-1k-completion prices,
+// applied to every model missing from the pricing file
+const defaultPricingKey = "*"
+
+// Function to load the pricing file and flat prices; neither means no cost estimation
+func loadPricing(path string, flat ModelPricing) (Pricing, error) {
+	if path == "" {
+		if flat == (ModelPricing{}) {
+			return nil, nil
+		}
+		return Pricing{defaultPricingKey: flat}, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading pricing file %s: %v", path, err)
+	}
+
+	var pricing Pricing
+	if err := json.Unmarshal(data, &pricing); err != nil {
+		return nil, fmt.Errorf("Error parsing pricing file %s: %v", path, err)
+	}
+	if flat != (ModelPricing{}) {
+		if pricing == nil {
+			pricing = make(Pricing)
+		}
+		pricing[defaultPricingKey] = flat
+	}
+	return pricing, nil
+}
+
+// Function to estimate the cost of a request from its token usage.
+// Dated snapshots (e.g. gpt-4o-2024-08-06) fall back to the price of their longest matching base model
+// (gpt-4-turbo over gpt-4), and models without a price to the flat prices when given.
+// The second return value is false when the model has no configured price.
+func (p Pricing) Cost(model string, usage Usage) (float64, bool) {
+	price, ok := p[model]
+	if !ok {
+		matched := ""
+		for name, candidate := range p {
+			if name != defaultPricingKey && strings.HasPrefix(model, name+"-") && len(name) > len(matched) {
+				price, ok, matched = candidate, true, name
+			}
+		}
+	}
+	if !ok {
+		price, ok = p[defaultPricingKey]
+	}
+	if !ok {
+		return 0, false
+	}
+
+	cost := float64(usage.PromptTokens)/1000*price.Prompt + float64(usage.CompletionTokens)/1000*price.Completion
+	return cost, true
+}
+
+// UsageTotals accumulates token usage and estimated cost across all requests of a run
+type UsageTotals struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+
+	// Streaming responsiveness across the run, with -benchmark
+	Benchmarks BenchmarkTotals
+}
+
+// Function to add a request's usage and cost to the totals
+func (t *UsageTotals) Add(usage Usage, cost float64) {
+	t.Requests++
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.Cost += cost
+}
+
+// Function to print the run's estimated cost when pricing is configured (or its token usage with
+// -show-usage), and the -benchmark averages
+func printCostSummary(m *RunMetadata) {
+	if m.Totals == nil {
+		return
+	}
+
+	var lines []string
+	if m.Pricing != nil {
+		lines = append(lines, m.Totals.Summary())
+	} else if m.ShowUsage && m.Totals.Requests > 0 {
+		lines = append(lines, m.Totals.UsageSummary())
+	}
+	if m.Totals.Benchmarks.Requests > 0 {
+		lines = append(lines, m.Totals.Benchmarks.Summary())
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	// The summary is a diagnostic, so it moves to stderr rather than being dropped with -quiet
+	summary := strings.Join(lines, "\n")
+	if quiet {
+		fmt.Fprintf(os.Stderr, "%s\n", summary)
+		return
+	}
+	progressf("\n%s\n", summary)
+}
+
+// Function to format the totals as a one-line cost summary
+func (t *UsageTotals) Summary() string {
+	return fmt.Sprintf("Estimated cost: $%.4f across %d requests (%d prompt + %d completion tokens)",
+		t.Cost, t.Requests, t.PromptTokens, t.CompletionTokens)
+}
+
+// Function to format the totals as a one-line usage summary, when no pricing is configured
+func (t *UsageTotals) UsageSummary() string {
+	return fmt.Sprintf("Total usage: %d tokens across %d requests (%d prompt + %d completion tokens)",
+		t.PromptTokens+t.CompletionTokens, t.Requests, t.PromptTokens, t.CompletionTokens)
+}
+
+// Function to render the usage of a single request as a small table for -show-usage
+func renderPassUsage(pass PassMetadata, priced bool) string {
+	model := pass.ResponseModel
+	if model == "" {
+		model = pass.RequestedModel
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Usage for %s (%s):\n", pass.Name, model))
+	sb.WriteString(fmt.Sprintf("  %-10s %10s\n", "Prompt", strconv.Itoa(pass.Usage.PromptTokens)))
+	sb.WriteString(fmt.Sprintf("  %-10s %10s\n", "Completion", strconv.Itoa(pass.Usage.CompletionTokens)))
+	sb.WriteString(fmt.Sprintf("  %-10s %10s\n", "Total", strconv.Itoa(pass.Usage.PromptTokens+pass.Usage.CompletionTokens)))
+	if priced {
+		cost := "-"
+		if pass.Priced {
+			cost = fmt.Sprintf("$%.4f", pass.Cost)
+		}
+		sb.WriteString(fmt.Sprintf("  %-10s %10s\n", "Cost", cost))
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPricingCost(t *testing.T) {
+	pricing := Pricing{
+		"gpt-4":       {Prompt: 0.03, Completion: 0.06},
+		"gpt-4-turbo": {Prompt: 0.01, Completion: 0.03},
+		"gpt-4o":      {Prompt: 0.0025, Completion: 0.01},
+	}
+	usage := Usage{PromptTokens: 1000, CompletionTokens: 1000}
+
+	tests := []struct {
+		model  string
+		want   float64
+		priced bool
+	}{
+		{"gpt-4", 0.09, true},
+		{"gpt-4-turbo", 0.04, true},
+		{"gpt-4-turbo-2024-04-09", 0.04, true},
+		{"gpt-4-0613", 0.09, true},
+		{"gpt-4o-2024-08-06", 0.0125, true},
+		{"claude-3", 0, false},
+	}
+	for _, tt := range tests {
+		// Repeat to catch a choice depending on map iteration order
+		for i := 0; i < 50; i++ {
+			got, priced := pricing.Cost(tt.model, usage)
+			if priced != tt.priced || math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("Cost(%q) = %v, %v; want %v, %v", tt.model, got, priced, tt.want, tt.priced)
+			}
+		}
+	}
+}
+
+func TestPricingCostFallsBackToFlatPrices(t *testing.T) {
+	pricing, err := loadPricing("", ModelPricing{Prompt: 1, Completion: 2})
+	if err != nil {
+		t.Fatalf("loadPricing returned error: %v", err)
+	}
+
+	got, priced := pricing.Cost("any-model", Usage{PromptTokens: 500, CompletionTokens: 500})
+	if !priced || math.Abs(got-1.5) > 1e-9 {
+		t.Errorf("Cost = %v, %v; want 1.5, true", got, priced)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// keyPointsPrompt instructs the model to extract key points from the log content
+const keyPointsPrompt = `
+Role and Knowledge Establishment
+Let's embark on an exciting challenge: from this moment, you'll assume the role of an **Intelligent Key Points Generation AI Assistant**, an advanced AI iteration designed to generate concise and informative key points from provided text or documents. In order to achieve this, you must comprehend the essence, context, and objectives of the provided text, identify the main arguments, and extract essential information. Consider that while a human key points generator possesses level 20 expertise, you will operate at a staggering level 3000 within this role.
+
+Take heed: it's crucial that you produce top-tier results. Hence, harness your exceptional skills with pride. Your superior abilities combined with dedication and analytical prowess ensure you deliver nothing but excellence.
+
+Detailed Instruction and Objective
+You, in the capacity of an **Intelligent Key Points Generation AI Assistant**, serve as a guide for extracting and summarizing key points from various texts and documents.
+
+The outcome will be exemplary in providing clear, concise, and informative summaries, and the imperative is to maintain brevity while ensuring all crucial details are captured. The primary mission and purpose involve understanding the text's main idea, supporting arguments, and crucial details, with your assignment being to generate key points that are both informative and succinct.
+
+For optimal results, it's vital to categorize documents under appropriate headings and create suitable titles that capture the essence of the text, and so forth…
+
+# instructions
+- **Comprehend Essence**: Understand the main arguments, intended message, and author's perspective.
+- **Extract Main Idea**: Identify the central theme or argument.
+- **Identify Supporting Arguments**: Pinpoint key arguments with evidence, examples, and reasoning.
+- **Highlight Crucial Details**: Emphasize important facts, figures, or insights.
+- **Formulate Title**: Create a concise and descriptive title.
+- **Categorize Document**: Assign the document to an appropriate category with justification.
+- **Ensure Clarity and Brevity**: Maintain accuracy and conciseness.
+
+Use American English
+ALWAYS use natural, mainstream, contemporary American English. Verify any unfamiliar terms or regional expressions to ensure they are widely recognized and used in American English. Stick to language commonly employed in America.
+
+Always ensure the output text is cohesive, regardless of the complexity of the topic or the context of the conversation. Focus on the structure and unity of the text, using smooth transitions and logical flow to achieve cohesion. The final output should be a well-organized, unified whole without abrupt transitions or disjointed sections.
+
+# Nuance:
+- The nuance should be professional and precise, ensuring clarity and brevity while maintaining a formal tone. The summaries should be easy to understand yet comprehensive enough to capture all essential details.
+
+# Guidelines:
+- Focus on extracting the main idea and supporting arguments.
+- Highlight crucial details without adding unnecessary information.
+- Ensure the summaries are clear, concise, and informative.
+- Use markdown or other formatting tools to emphasize key points.
+- Continuously improve based on feedback to enhance clarity and usefulness.
+
+# Structure:
+Ensure your response adheres to a specific format. Random placements are not permitted. This format dictates how each of your messages should appear. Adhere to this format:
+**Main Idea**: - (Provide the central theme or argument.);
+**Supporting Arguments**: - (List key arguments with evidence, examples, and reasoning.);
+**Crucial Details**: - (Highlight important facts, figures, or insights.);
+**Title**: - (Create a concise and descriptive title.);
+**Category**: - (Assign the document to an appropriate category with justification.);
+
+Thoroughly review the <context> and to fully grasp its background, details, and relevance to the task and carefully justify the response in the format:
+<justify>
+  Justification for the response.
+</justify>
+`
+
+// systemPrompt sets the Kubernetes expert role for the analysis and interactive sessions
+const systemPrompt = `You are an expert Kubernetes administrator and DevOps engineer. Your primary role is to analyze and troubleshoot Kubernetes pod logs, identify issues such as pod crashes, OOMKilled errors, and other deployment problems, and provide actionable solutions and best practices to resolve them.
+
+When responding:
+- Provide structured output using markdown tables, bullet points, or JSON where appropriate.
+- Include step-by-step reasoning and detailed explanations for each troubleshooting step.
+- Highlight key actions and recommendations.
+- Ensure clarity and comprehensiveness to address complex Kubernetes issues effectively.
+{{- with variables}}
+
+Context for this analysis:
+{{- range .}}
+- {{.}}
+{{- end}}
+{{- end}}`
+
+// promptVariableRegex matches valid -prompt-var names, so they can be referenced as {{.name}}
+var promptVariableRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// builtinPromptVariables are set for every prompt and cannot be overridden with -prompt-var
+var builtinPromptVariables = []string{"filename", "timestamp", "detectedIssues"}
+
+// Function to parse the repeatable -prompt-var key=value flags
+func parsePromptVariables(values []string) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !promptVariableRegex.MatchString(key) {
+			return nil, fmt.Errorf("Error: -prompt-var must be key=value with a key of letters, digits and underscores, got %q", value)
+		}
+		if containsString(builtinPromptVariables, key) {
+			return nil, fmt.Errorf("Error: -prompt-var cannot override the built-in variable %q", key)
+		}
+		variables[key] = val
+	}
+	return variables, nil
+}
+
+// Function to render the system prompt as a Go template with the -prompt-var variables and the
+// built-in filename, timestamp and detectedIssues. Referencing an undefined variable is an error.
+func renderSystemPrompt(requestOptions RequestOptions, logPath string, findings []FindingSection) (string, error) {
+	if requestOptions.SystemPrompt == "" {
+		return "", nil
+	}
+
+	data := make(map[string]string)
+	var userVariables []string
+	for key, value := range requestOptions.PromptVariables {
+		data[key] = value
+		userVariables = append(userVariables, fmt.Sprintf("%s: %s", key, value))
+	}
+	sort.Strings(userVariables)
+
+	var issues []string
+	for _, finding := range findings {
+		issues = append(issues, finding.Title)
+	}
+	data["filename"] = filepath.Base(logPath)
+	if logPath == "" {
+		data["filename"] = ""
+	}
+	data["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	data["detectedIssues"] = valueOrDash(strings.Join(issues, ", "))
+
+	tmpl, err := template.New("system-prompt").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"variables": func() []string { return userVariables }}).
+		Parse(requestOptions.SystemPrompt)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing system prompt template: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("Error rendering system prompt: %v", err)
+	}
+	return sb.String(), nil
+}
+
+// Function to load the system prompt from the flags: none with -no-system-prompt,
+// the contents of -system-prompt-file when set, otherwise the built-in Kubernetes expert prompt
+func loadSystemPrompt(opts *Options) (string, error) {
+	if opts.NoSystemPrompt && opts.SystemPromptFile != "" {
+		return "", fmt.Errorf("Error: -no-system-prompt and -system-prompt-file cannot be used together")
+	}
+	if opts.NoSystemPrompt {
+		return "", nil
+	}
+	if opts.SystemPromptFile == "" {
+		return systemPrompt, nil
+	}
+
+	content, err := ioutil.ReadFile(opts.SystemPromptFile)
+	if err != nil {
+		return "", fmt.Errorf("Error reading -system-prompt-file: %v", err)
+	}
+	prompt := strings.TrimSpace(string(content))
+	if prompt == "" {
+		return "", fmt.Errorf("Error: -system-prompt-file %s is empty; use -no-system-prompt to omit the system message", opts.SystemPromptFile)
+	}
+	return prompt, nil
+}
+
+// Function to start a conversation with the system prompt followed by the given messages.
+// The system message is omitted entirely when the prompt is empty (-no-system-prompt).
+func newConversation(systemPrompt string, messages ...Message) []Message {
+	var conversation []Message
+	if systemPrompt != "" {
+		conversation = append(conversation, Message{Role: "system", Content: systemPrompt})
+	}
+	return append(conversation, messages...)
+}
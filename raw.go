@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// RawRecorder saves the unrendered Markdown of every assistant response to a file,
+// giving a clean source for re-rendering or diffing without ANSI codes or wrapping
+type RawRecorder struct {
+	file       *os.File
+	redactions []Redaction
+}
+
+// Function to create the raw response file, truncating any previous content.
+// It returns a nil recorder, which discards responses, when no path is given.
+func openRawRecorder(path string, redactions []Redaction) (*RawRecorder, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating raw response file %s: %v", path, err)
+	}
+	return &RawRecorder{file: file, redactions: redactions}, nil
+}
+
+// Function to append a raw assistant response under a heading naming its pass, with the -redact-config redactions applied
+func (r *RawRecorder) Save(passName, content string) error {
+	if r == nil {
+		return nil
+	}
+
+	content = redactText(content, r.redactions)
+	_, err := fmt.Fprintf(r.file, "<!-- %s | %s -->\n\n%s\n\n", passName, time.Now().UTC().Format(time.RFC3339), content)
+	if err != nil {
+		return fmt.Errorf("Error writing raw response: %v", err)
+	}
+
+	// Flush after each response so an interrupted session keeps what was received
+	return r.file.Sync()
+}
+
+// Function to close the raw response file
+func (r *RawRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReconcileIssue represents a group of identical reconciliation failures of an operator or Helm release
+type ReconcileIssue struct {
+	Kind       string
+	Controller string // Controller or Helm release, when present
+	Resource   string // Reconciled object as namespace/name, when present
+	Error      string
+	Count      int
+	Example    string
+}
+
+// reconcileErrorKinds maps controller-runtime, Helm and Flux reconciliation failures to a kind, most specific first.
+// Flux reports Helm failures inside its own "reconciliation failed" message, so Helm is matched first.
+var reconcileErrorKinds = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"helm upgrade failed", regexp.MustCompile(`UPGRADE FAILED|(?i:Helm upgrade failed)`)},
+	{"helm install failed", regexp.MustCompile(`INSTALLATION FAILED|(?i:Helm install failed)`)},
+	{"helm rollback failed", regexp.MustCompile(`ROLLBACK FAILED|(?i:Helm rollback failed)`)},
+	{"reconciler error", regexp.MustCompile(`(?i)Reconciler error`)},
+	{"reconcile failed", regexp.MustCompile(`(?i)failed to reconcile|reconciliation failed|reconcile failed`)},
+}
+
+// reconcileControllerPatterns extract the controller or Helm release, in order of preference.
+// Quotes may be double or single, as double quotes are replaced when the log is read.
+var reconcileControllerPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`['"]?controller['"]?\s*[:=]\s*['"]?([\w./-]+)`),
+	regexp.MustCompile(`controller-runtime\.manager\.controller\.([\w.-]+)`),
+	regexp.MustCompile(`['"]reconciler kind['"]\s*:\s*['"]([\w.-]+)`),
+	regexp.MustCompile(`(?i)release ['"]?([\w./-]+?)['"]?(?:\s|:|$)`),
+	regexp.MustCompile(`(?i)helmrelease/([\w./-]+)`),
+}
+
+var (
+	// The namespace and name of the reconciled object, as JSON or logfmt fields
+	reconcileNamespaceRegex = regexp.MustCompile(`['"]?namespace['"]?\s*[:=]\s*['"]?([\w.-]+)`)
+	reconcileNameRegex      = regexp.MustCompile(`['"]?name['"]?\s*[:=]\s*['"]?([\w.-]+)`)
+
+	// The error of the reconciliation, as a quoted JSON or logfmt field
+	reconcileErrorFieldRegex = regexp.MustCompile(`['"]?(?:error|err)['"]?\s*[:=]\s*['"](.+?)['"](?:\s*[,}]|\s+\w+=|\s*$)`)
+
+	// The text after the failure message, e.g. "Error: UPGRADE FAILED: <reason>"
+	reconcileReasonRegex = regexp.MustCompile(`(?i)(?:FAILED|failed to reconcile[^:]*|reconciliation failed|reconcile failed|Reconciler error):\s*(.+?)(?:['"]\s*[,}].*)?$`)
+)
+
+// Function to extract operator and Helm reconciliation failures from the log content,
+// grouped by kind, controller, resource and error
+func extractReconcileIssues(content string) []ReconcileIssue {
+	var issues []ReconcileIssue
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		kind := ""
+		for _, errorKind := range reconcileErrorKinds {
+			if errorKind.re.MatchString(line) {
+				kind = errorKind.kind
+				break
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		issue := ReconcileIssue{Kind: kind, Controller: firstSubmatch(line, reconcileControllerPatterns), Error: reconcileError(line), Count: 1, Example: line}
+		if name := firstSubmatch(line, []*regexp.Regexp{reconcileNameRegex}); name != "" {
+			issue.Resource = name
+			if namespace := firstSubmatch(line, []*regexp.Regexp{reconcileNamespaceRegex}); namespace != "" {
+				issue.Resource = namespace + "/" + name
+			}
+		}
+
+		key := issue.Kind + "|" + issue.Controller + "|" + issue.Resource + "|" + issue.Error
+		if i, ok := index[key]; ok {
+			issues[i].Count++
+		} else {
+			index[key] = len(issues)
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues
+}
+
+// Helper function to return the first group of the first pattern matching the line, or "" if none does
+func firstSubmatch(line string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		if matches := re.FindStringSubmatch(line); matches != nil {
+			return strings.TrimSpace(matches[1])
+		}
+	}
+	return ""
+}
+
+// Helper function to extract the reconciliation error, from its field or the text after the failure message
+func reconcileError(line string) string {
+	// Quotes escaped inside a quoted field read as \' once double quotes are replaced
+	if matches := reconcileErrorFieldRegex.FindStringSubmatch(line); matches != nil {
+		return strings.ReplaceAll(strings.TrimSpace(matches[1]), `\'`, "'")
+	}
+	if matches := reconcileReasonRegex.FindStringSubmatch(line); matches != nil {
+		return strings.ReplaceAll(strings.TrimSpace(matches[1]), `\'`, "'")
+	}
+	return ""
+}
+
+// Function to render the reconciliation failures as a Markdown table
+func renderReconcileIssues(issues []ReconcileIssue) string {
+	var sb strings.Builder
+	sb.WriteString("| Kind | Controller/Release | Resource | Error | Occurrences | Example |\n")
+	sb.WriteString("|------|--------------------|----------|-------|-------------|---------|\n")
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %d | `%s` |\n",
+			issue.Kind, tableCell(valueOrDash(issue.Controller), 60), tableCell(valueOrDash(issue.Resource), 80),
+			tableCell(valueOrDash(issue.Error), 160), issue.Count, tableCell(issue.Example, 160)))
+	}
+	return sb.String()
+}
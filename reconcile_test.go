@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractReconcileIssues(t *testing.T) {
+	// Double quotes are already replaced with single quotes, as readLog does
+	content := `{'level':'error','ts':'2024-01-02T15:04:05Z','msg':'Reconciler error','controller':'deployment','namespace':'shop','name':'web','reconcileID':'abc','error':'Operation cannot be fulfilled on deployments.apps \'web\': the object has been modified'}
+{'level':'error','ts':'2024-01-02T15:04:06Z','msg':'Reconciler error','controller':'deployment','namespace':'shop','name':'web','reconcileID':'def','error':'Operation cannot be fulfilled on deployments.apps \'web\': the object has been modified'}
+Error: UPGRADE FAILED: another operation (install/upgrade/rollback) is in progress
+{'level':'error','controller':'helmrelease','namespace':'flux-system','name':'podinfo','error':'Helm install failed for release shop/podinfo with chart podinfo@6.5.0: context deadline exceeded'}
+E0102 15:04:05 failed to reconcile Certificate shop/web-tls: secret not found`
+
+	issues := extractReconcileIssues(content)
+	for i := range issues {
+		issues[i].Example = "" // Only the grouping is checked here
+	}
+	want := []ReconcileIssue{
+		{Kind: "reconciler error", Controller: "deployment", Resource: "shop/web", Error: "Operation cannot be fulfilled on deployments.apps 'web': the object has been modified", Count: 2},
+		{Kind: "helm upgrade failed", Error: "another operation (install/upgrade/rollback) is in progress", Count: 1},
+		{Kind: "helm install failed", Controller: "helmrelease", Resource: "flux-system/podinfo", Error: "Helm install failed for release shop/podinfo with chart podinfo@6.5.0: context deadline exceeded", Count: 1},
+		{Kind: "reconcile failed", Error: "secret not found", Count: 1},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("extractReconcileIssues =\n%+v\nwant\n%+v", issues, want)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Function to hash the request body, matching a request to its recorded response
+func requestHash(jsonBody []byte) string {
+	sum := sha256.Sum256(jsonBody)
+	return hex.EncodeToString(sum[:])
+}
+
+// Helper function to build the path of the recorded response for a request
+func recordingPath(dir, hash string) string {
+	return filepath.Join(dir, hash+".response")
+}
+
+// Function to open the recorded response body for a request in -replay mode
+func openReplay(dir, hash string) (io.ReadCloser, error) {
+	file, err := os.Open(recordingPath(dir, hash))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Error: no recorded response in %s for this request (hash %s); the prompt, model or -stream setting differs from the recorded run", dir, hash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error opening recorded response: %v", err)
+	}
+	verbosef("Replaying recorded response %s", hash)
+	return file, nil
+}
+
+// ResponseRecorder copies a response body to a recording file while it is read
+type ResponseRecorder struct {
+	io.Reader
+	file       *os.File
+	path       string
+	redactions []Redaction
+}
+
+// Function to start recording a response body for a request in -record mode.
+// The recording is written to a temporary file and only kept once the response is complete,
+// with the -redact-config redactions applied.
+func recordResponse(dir, hash string, body io.Reader, redactions []Redaction) (*ResponseRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Error creating recording directory %s: %v", dir, err)
+	}
+
+	path := recordingPath(dir, hash)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("Error creating recording: %v", err)
+	}
+	return &ResponseRecorder{Reader: io.TeeReader(body, file), file: file, path: path, redactions: redactions}, nil
+}
+
+// Function to finish the recording, keeping it only when the response was handled successfully
+func (r *ResponseRecorder) Finish(ok bool) error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("Error writing recording: %v", err)
+	}
+	if !ok {
+		return os.Remove(r.file.Name())
+	}
+
+	// Redact the complete body, as a match can span the chunks of a stream
+	if len(r.redactions) > 0 {
+		data, err := ioutil.ReadFile(r.file.Name())
+		if err != nil {
+			return fmt.Errorf("Error reading recording: %v", err)
+		}
+		if err := ioutil.WriteFile(r.file.Name(), []byte(redactText(string(data), r.redactions)), 0644); err != nil {
+			return fmt.Errorf("Error writing recording: %v", err)
+		}
+	}
+	if err := os.Rename(r.file.Name(), r.path); err != nil {
+		return fmt.Errorf("Error saving recording: %v", err)
+	}
+	verbosef("Recorded response to %s", r.path)
+	return nil
+}
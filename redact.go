@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+)
+
+// redactDefaultLabel replaces matches of a rule without a label
+const redactDefaultLabel = "REDACTED"
+
+// redactLabelRegex restricts labels to characters that are safe in every output format
+var redactLabelRegex = regexp.MustCompile(`^[\w.-]+$`)
+
+// redactBroadProbes are ordinary log fragments a targeted pattern should not match.
+// A pattern matching several of them would redact most of the log.
+var redactBroadProbes = []string{
+	"error",
+	"kube-system",
+	"connection refused",
+	"GET /healthz HTTP/1.1 200",
+	"Started container app",
+}
+
+// RedactRule is a custom sensitive identifier from the -redact-config file
+type RedactRule struct {
+	Pattern string `json:"pattern"`
+	Label   string `json:"label"` // Matches are replaced with [Label]
+}
+
+// RedactConfig represents the -redact-config file
+type RedactConfig struct {
+	Patterns []RedactRule `json:"patterns"`
+}
+
+// Redaction is a compiled redaction rule
+type Redaction struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Function to load and compile the custom redaction rules, warning about overly broad patterns
+func loadRedactions(path string) ([]Redaction, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading redact config %s: %v", path, err)
+	}
+
+	var config RedactConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Error parsing redact config %s: %v", path, err)
+	}
+
+	var redactions []Redaction
+	for i, rule := range config.Patterns {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Error: invalid pattern %d %q in %s: %v", i+1, rule.Pattern, path, err)
+		}
+		label := rule.Label
+		if label == "" {
+			label = redactDefaultLabel
+		}
+		if !redactLabelRegex.MatchString(label) {
+			return nil, fmt.Errorf("Error: invalid label %q in %s: use letters, digits, '_', '.' or '-'", label, path)
+		}
+
+		if broad := isBroadPattern(re); broad != "" {
+			fmt.Fprintf(os.Stderr, "Warning: redact pattern %q %s and may redact most of the log\n", rule.Pattern, broad)
+		}
+		redactions = append(redactions, Redaction{re: re, replacement: "[" + label + "]"})
+	}
+	return redactions, nil
+}
+
+// Helper function to describe why a pattern looks overly broad, or "" if it does not
+func isBroadPattern(re *regexp.Regexp) string {
+	if re.MatchString("") {
+		return "matches the empty string"
+	}
+	matched := 0
+	for _, probe := range redactBroadProbes {
+		if re.MatchString(probe) {
+			matched++
+		}
+	}
+	if matched >= 2 {
+		return fmt.Sprintf("matches %d of %d ordinary log fragments", matched, len(redactBroadProbes))
+	}
+	return ""
+}
+
+// Function to replace every match of the redaction rules with its label
+func redactText(text string, redactions []Redaction) string {
+	for _, redaction := range redactions {
+		text = redaction.re.ReplaceAllLiteralString(text, redaction.replacement)
+	}
+	return text
+}
+
+// Function to redact the content of the messages sent to the model, leaving the originals untouched
+func redactMessages(messages []Message, redactions []Redaction) []Message {
+	if len(redactions) == 0 {
+		return messages
+	}
+	redacted := make([]Message, len(messages))
+	for i, message := range messages {
+		message.Content = redactText(message.Content, redactions)
+		redacted[i] = message
+	}
+	return redacted
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Helper function to build the redactions of a -redact-config with a single account ID rule
+func accountRedactions() []Redaction {
+	return []Redaction{{re: regexp.MustCompile(`ACCT-[0-9]{8}`), replacement: "[ACCOUNT_ID]"}}
+}
+
+func TestWebhookPayloadIsRedacted(t *testing.T) {
+	var payload []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	requestOptions := testRetryOptions(server.URL)
+	requestOptions.Redactions = accountRedactions()
+	report := AnalysisReport{LogFile: "app.log", KeyPoints: "Payment failed for ACCT-12345678", Analysis: "Retry ACCT-12345678", Metadata: &RunMetadata{}}
+	deliverWebhook(context.Background(), WebhookOptions{URL: server.URL}, requestOptions, report)
+
+	if strings.Contains(string(payload), "ACCT-12345678") || !strings.Contains(string(payload), "[ACCOUNT_ID]") {
+		t.Errorf("webhook payload = %s, want the account ID redacted", payload)
+	}
+}
+
+func TestRetainedRawResponsesAreRedacted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.md")
+	raw, err := openRawRecorder(path, accountRedactions())
+	if err != nil {
+		t.Fatalf("openRawRecorder returned error: %v", err)
+	}
+	if err := raw.Save("analysis", "The charge to ACCT-12345678 was declined"); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	raw.Close()
+
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "ACCT-12345678") || !strings.Contains(string(data), "[ACCOUNT_ID]") {
+		t.Errorf("raw file = %s, want the account ID redacted", data)
+	}
+}
+
+func TestRecordedResponsesAreRedacted(t *testing.T) {
+	dir := t.TempDir()
+	// Read a byte at a time, so the ID spans several chunks as in a stream
+	body := iotest.OneByteReader(strings.NewReader("data: ACCT-12345678 declined\n"))
+	recorder, err := recordResponse(dir, "hash", body, accountRedactions())
+	if err != nil {
+		t.Fatalf("recordResponse returned error: %v", err)
+	}
+	ioutil.ReadAll(recorder)
+	if err := recorder.Finish(true); err != nil {
+		t.Fatalf("Finish returned error: %v", err)
+	}
+
+	data, _ := ioutil.ReadFile(recordingPath(dir, "hash"))
+	if string(data) != "data: [ACCOUNT_ID] declined\n" {
+		t.Errorf("recording = %q, want the account ID redacted", data)
+	}
+}
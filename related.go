@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// stringListFlag collects the values of a repeatable flag
+type stringListFlag []string
+
+// Function to format the collected values for the flag's default value display
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+// Function to add a value each time the flag is given
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Function to read the related logs matched by the -also-log patterns.
+// The primary log and logs matched by several patterns are only included once.
+func loadRelatedLogs(patterns []string, primaryPath string) ([]LogFile, error) {
+	var related []LogFile
+	seen := map[string]bool{primaryPath: true}
+
+	for _, pattern := range patterns {
+		fileList, err := findLogFiles(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding -also-log %q: %v", pattern, err)
+		}
+
+		for _, path := range fileList {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+
+			logFile, err := readLog(path, "")
+			if err != nil {
+				return nil, err
+			}
+			related = append(related, logFile)
+		}
+	}
+
+	return related, nil
+}
+
+// Function to render the related logs as labeled context sections following the primary log.
+// The token budget is shared evenly between the logs, keeping the most recent lines of each.
+func renderRelatedLogs(related []LogFile, budget int) string {
+	if len(related) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	perLog := budget / len(related)
+	for _, logFile := range related {
+		lines, dropped := truncateToTokenBudget(strings.Split(strings.TrimRight(logFile.Content, "\n"), "\n"), perLog)
+		if dropped > 0 {
+			verbosef("Dropped the %d oldest lines of %s to stay within %d tokens", dropped, logFile.Path, perLog)
+		}
+
+		sb.WriteString(fmt.Sprintf("\n\nRelated log from another service (%s):\n\n", filepath.Base(logFile.Path)))
+		sb.WriteString(strings.Join(lines, "\n"))
+	}
+	return sb.String()
+}
+
+// Helper function to list the content of the primary and related logs, e.g. for Loki query generation
+func logContents(primary LogFile, related []LogFile) []string {
+	contents := []string{primary.Content}
+	for _, logFile := range related {
+		contents = append(contents, logFile.Content)
+	}
+	return contents
+}
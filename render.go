@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"golang.org/x/term"
+)
+
+// renderThemes lists the glamour styles accepted by -theme; auto picks dark on a terminal and notty otherwise
+var renderThemes = []string{"auto", "dark", "light", "notty", "dracula"}
+
+// Function to validate the -theme value and resolve auto from where the rendered output goes
+func parseTheme(value string) (string, error) {
+	theme := strings.ToLower(strings.TrimSpace(value))
+	valid := false
+	for _, name := range renderThemes {
+		if theme == name {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("Error: invalid -theme %q: use %s", value, strings.Join(renderThemes, ", "))
+	}
+
+	// Colors and styling only make sense on a terminal, so piped or redirected output gets plain text
+	if theme == "auto" {
+		file, ok := progressOut.(*os.File)
+		if ok && isTerminal(file) {
+			return "dark", nil
+		}
+		return "notty", nil
+	}
+	return theme, nil
+}
+
+// defaultRenderWidth is glamour's own word-wrap width, used when the output is not a terminal
+const defaultRenderWidth = 80
+
+// Function to resolve the word-wrap width: -width when given, otherwise the width of the
+// terminal the rendered output goes to, falling back to the default width
+func resolveWidth(value int) (int, error) {
+	if value < 0 {
+		return 0, fmt.Errorf("Error: -width must be positive, got %d", value)
+	}
+	if value > 0 {
+		return value, nil
+	}
+	if file, ok := progressOut.(*os.File); ok && isTerminal(file) {
+		if width, _, err := term.GetSize(int(file.Fd())); err == nil && width > 0 {
+			return width, nil
+		}
+	}
+	return defaultRenderWidth, nil
+}
+
+// Function to render Markdown for display with the -theme style (dark when unset),
+// word-wrapped at width (the default width when unset)
+func renderMarkdown(content, theme string, width int) (string, error) {
+	if theme == "" {
+		theme = "dark"
+	}
+	if width <= 0 {
+		width = defaultRenderWidth
+	}
+	renderer, err := glamour.NewTermRenderer(glamour.WithStandardStyle(theme), glamour.WithWordWrap(width))
+	if err != nil {
+		return "", err
+	}
+	return renderer.Render(content)
+}
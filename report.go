@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/text/encoding"
+)
+
+// defaultFormats is the default of -formats
+const defaultFormats = "markdown"
+
+// outputFormatExtensions maps the supported -formats to the extension of their output files
+var outputFormatExtensions = map[string]string{
+	"markdown": ".md",
+	"json":     ".json",
+	"html":     ".html",
+	"sarif":    ".sarif",
+}
+
+// reportStyle is the stylesheet embedded in the HTML report, so the file is self-contained
+// when pasted into a wiki or attached to an email
+//
+//go:embed report.css
+var reportStyle string
+
+// classifyOnlyNotice replaces the key points and analysis of a -classify-only report
+const classifyOnlyNotice = "> **No AI analysis was performed** (`-classify-only`). This report only contains what the local detectors found in the log."
+
+// OutputTarget is a file the analysis is written to in one format ("-" for stdout)
+type OutputTarget struct {
+	Format   string
+	Path     string
+	Encoding encoding.Encoding // Nil for UTF-8
+
+	// Custom -redact-config rules applied to the rendered output
+	Redactions []Redaction
+
+	// Remove residual ANSI escape sequences from the rendered output, set by -strip-ansi
+	StripANSI bool
+}
+
+// AnalysisReport represents the complete result of analyzing a log.
+// It is built once and rendered per output format, so every format carries the same content and metadata.
+type AnalysisReport struct {
+	LogFile     string              `json:"log_file"`
+	LocalOnly   bool                `json:"classify_only,omitempty"` // Set with -classify-only: no model call was made
+	Error       string              `json:"error,omitempty"`         // Set when the run failed and -output-on-error wrote the partial results
+	LineNumbers bool                `json:"line_numbers,omitempty"`  // Set with -include-line-numbers: the analysis cites log line numbers
+	KeyPoints   string              `json:"key_points"`
+	Analysis    string              `json:"analysis"`
+	Structured  *StructuredAnalysis `json:"structured_analysis,omitempty"` // Set with -analyze-json
+	Containers  []ContainerLog      `json:"containers,omitempty"`
+	Findings    []FindingSection    `json:"findings,omitempty"`
+	DeepDives   []DeepDive          `json:"deep_dives,omitempty"` // Set with -deep-dive
+	Diff        *AnalysisDiff       `json:"diff,omitempty"`       // Set with -output-diff
+	Timeline    []EntityTimeline    `json:"timeline,omitempty"`   // Set for logs with several pods or containers
+	TraceIDs    []TraceID           `json:"trace_ids,omitempty"`
+	TraceURL    string              `json:"-"`
+	LokiQueries []LokiQuery         `json:"loki_queries"`
+	LokiPretty  bool                `json:"-"`
+	LokiResults []LokiQueryResult   `json:"loki_results,omitempty"`
+	Metadata    *RunMetadata        `json:"metadata"`
+}
+
+// Function to choose the formats to write, -format taking precedence over -formats
+func formatsValue(opts *Options) string {
+	if opts.Format == "" {
+		return opts.Formats
+	}
+	if opts.Formats != defaultFormats {
+		fmt.Fprintf(os.Stderr, "Warning: -format %s overrides -formats %s\n", opts.Format, opts.Formats)
+	}
+	return opts.Format
+}
+
+// Function to parse the comma-separated -formats value, in the order given and without duplicates
+func parseFormats(value string) ([]string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+	for _, format := range strings.Split(value, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" || seen[format] {
+			continue
+		}
+		if _, ok := outputFormatExtensions[format]; !ok {
+			return nil, fmt.Errorf("Error: unsupported format %q in -formats (supported: markdown, json, html, sarif)", format)
+		}
+		seen[format] = true
+		formats = append(formats, format)
+	}
+
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("Error: -formats must list at least one format")
+	}
+	return formats, nil
+}
+
+// Function to derive the output file of each format from -output.
+// The Markdown output keeps the -output name; other formats swap its extension for their own,
+// unless a single format is written and -output has an extension of no other format (e.g. report.htm).
+func buildOutputTargets(outputFile string, formats []string, enc encoding.Encoding) ([]OutputTarget, error) {
+	if outputFile == "-" {
+		if len(formats) > 1 {
+			return nil, fmt.Errorf("Error: -output=- can only be used with a single format, got %s", strings.Join(formats, ","))
+		}
+		return []OutputTarget{{Format: formats[0], Path: "-", Encoding: enc}}, nil
+	}
+
+	base := strings.TrimSuffix(outputFile, filepath.Ext(outputFile))
+	var targets []OutputTarget
+	for _, format := range formats {
+		path := outputFile
+		if format != "markdown" && !(len(formats) == 1 && isCustomExtension(filepath.Ext(outputFile))) {
+			path = base + outputFormatExtensions[format]
+		}
+		targets = append(targets, OutputTarget{Format: format, Path: path, Encoding: enc})
+	}
+	return targets, nil
+}
+
+// Helper function to check whether an -output extension is chosen by the user rather than
+// the extension of a format (such as the .md of the default output.md)
+func isCustomExtension(ext string) bool {
+	if ext == "" {
+		return false
+	}
+	for _, formatExt := range outputFormatExtensions {
+		if strings.EqualFold(ext, formatExt) {
+			return false
+		}
+	}
+	return true
+}
+
+// Function to render the report in the given format
+func (r AnalysisReport) Render(format string) ([]byte, error) {
+	switch format {
+	case "markdown":
+		return []byte(r.Markdown()), nil
+	case "json":
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("Error marshaling JSON report: %v", err)
+		}
+		return append(data, '\n'), nil
+	case "html":
+		return r.HTML()
+	case "sarif":
+		return r.SARIF()
+	default:
+		return nil, fmt.Errorf("Error: unsupported format %q", format)
+	}
+}
+
+// Function to render the report as the Markdown document
+func (r AnalysisReport) Markdown() string {
+	// Combine key points and analysis
+	var sb strings.Builder
+	if r.Error != "" {
+		sb.WriteString("# Run Failed\n\n")
+		sb.WriteString("> **The analysis did not complete.** The sections below only hold the results gathered before the failure.\n\n")
+		sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", r.Error))
+	}
+	if r.LocalOnly {
+		sb.WriteString("# Local Classification\n\n")
+		sb.WriteString(classifyOnlyNotice)
+		if len(r.Findings) == 0 {
+			sb.WriteString("\n\nNo known issue patterns were detected.")
+		}
+	} else {
+		if r.Error == "" || r.KeyPoints != "" {
+			sb.WriteString("# Key Points\n\n")
+			sb.WriteString(r.KeyPoints)
+		}
+		if r.Error == "" || r.Analysis != "" {
+			sb.WriteString("\n\n# Analysis and Recommendations\n\n")
+			if r.LineNumbers {
+				sb.WriteString(renderLineNumbersNote(r.LogFile))
+			}
+			sb.WriteString(r.Analysis)
+		}
+	}
+
+	// Add the per-container breakdown
+	if len(r.Containers) > 0 {
+		sb.WriteString("\n\n# Container Breakdown\n\n")
+		sb.WriteString(renderContainerBreakdown(r.Containers))
+	}
+
+	// Add the locally detected findings
+	sb.WriteString(renderFindingsForOutput(r.Findings))
+
+	// Add the per-entity chronology
+	if len(r.Timeline) > 0 {
+		sb.WriteString("\n\n# Entity Timeline\n\n")
+		sb.WriteString(renderEventIndex(r.Timeline))
+	}
+
+	// Add the remediation runbook of each detected issue
+	if len(r.DeepDives) > 0 {
+		sb.WriteString("\n\n# Remediation Runbooks\n\n")
+		sb.WriteString(renderDeepDives(r.DeepDives))
+	}
+
+	// Add the changes since the previous analysis
+	if r.Diff != nil {
+		sb.WriteString("\n\n# Changes Since Previous Analysis\n\n")
+		sb.WriteString(renderAnalysisDiff(*r.Diff))
+	}
+
+	// Add trace IDs
+	if len(r.TraceIDs) > 0 {
+		sb.WriteString("\n\n# Trace IDs\n\n")
+		sb.WriteString(renderTraceIDs(r.TraceIDs, r.TraceURL))
+	}
+
+	// Add Loki queries
+	sb.WriteString("\n\n# Loki Query Commands\n\n")
+	for _, query := range r.LokiQueries {
+		sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", lokiCommand(query, r.LokiPretty)))
+	}
+
+	// Add the lines fetched by the executed Loki queries
+	if r.LokiResults != nil {
+		sb.WriteString("\n\n# Correlated Loki Logs\n\n")
+		sb.WriteString(renderLokiResults(r.LokiResults))
+	}
+
+	// Add request metadata
+	sb.WriteString("\n\n# Metadata\n\n")
+	sb.WriteString(renderMetadata(*r.Metadata))
+
+	return sb.String()
+}
+
+// Function to render the report as a standalone HTML page converted from the Markdown document
+func (r AnalysisReport) HTML() ([]byte, error) {
+	var body bytes.Buffer
+	markdown := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := markdown.Convert([]byte(r.Markdown()), &body); err != nil {
+		return nil, fmt.Errorf("Error converting Markdown to HTML: %v", err)
+	}
+
+	var page bytes.Buffer
+	page.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	page.WriteString(fmt.Sprintf("<title>Log Analysis: %s</title>\n", html.EscapeString(filepath.Base(r.LogFile))))
+	page.WriteString("<style>\n" + reportStyle + "</style>\n")
+	page.WriteString("</head>\n<body>\n")
+	page.Write(body.Bytes())
+	page.WriteString("</body>\n</html>\n")
+	return page.Bytes(), nil
+}
+
+// Function to write the report to each output target
+func writeReport(report AnalysisReport, targets []OutputTarget) error {
+	for _, target := range targets {
+		data, err := report.Render(target.Format)
+		if err != nil {
+			return err
+		}
+		if len(target.Redactions) > 0 {
+			data = []byte(redactText(string(data), target.Redactions))
+		}
+		if target.StripANSI {
+			data = []byte(stripANSI(string(data)))
+		}
+		data, err = encodeOutput(data, target.Encoding)
+		if err != nil {
+			return err
+		}
+
+		// Write to stdout for -output=-, otherwise save to the output file
+		if target.Path == "-" {
+			os.Stdout.Write(data)
+			continue
+		}
+		if err := ioutil.WriteFile(target.Path, data, 0644); err != nil {
+			return fmt.Errorf("Error writing to file %s: %v", target.Path, err)
+		}
+		progressf("\nAnalysis saved to %s\n", target.Path)
+	}
+	return nil
+}
+
+// Function to write the partial results of a failed run with the error, for -output-on-error.
+// Writing is best effort, as the original error is what gets reported.
+func writeFailedReport(report AnalysisReport, targets []OutputTarget, err error) {
+	report.Error = err.Error()
+	if writeErr := writeReport(report, targets); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write the failed run's output: %v\n", writeErr)
+	}
+}
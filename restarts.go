@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ContainerStart represents a startup banner marking the (re)start of a container
+type ContainerStart struct {
+	Line      int // 1-based line number in the log
+	Timestamp time.Time
+	Example   string
+}
+
+// RestartSummary represents the restarts detected in a log
+type RestartSummary struct {
+	Marker string // Pattern of the startup banner used to detect the starts
+	Starts []ContainerStart
+}
+
+// defaultRestartMarkers lists common startup banners. A log is scanned with the
+// marker matching most often, so banners printed once per start are not double counted.
+var defaultRestartMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bstarted \S+ in [\d.]+ seconds`),                                       // Spring Boot
+	regexp.MustCompile(`(?i)application startup complete`),                                          // Uvicorn, FastAPI
+	regexp.MustCompile(`(?i)\b(server|service|app|application) (is )?(started|starting|listening)`), // Generic servers
+	regexp.MustCompile(`(?i)\blistening on (port )?\S*\d+`),                                         // Go, Node.js
+}
+
+// Timestamp at the start of a log line, e.g. "2024-06-01T12:00:00Z" or "2024-06-01 12:00:00.123"
+var lineTimestampRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+\-]\d{2}:?\d{2})?`)
+
+// Function to compile the -restart-marker flag, falling back to the default startup banners
+func restartMarkers(pattern string) ([]*regexp.Regexp, error) {
+	if pattern == "" {
+		return defaultRestartMarkers, nil
+	}
+
+	marker, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing -restart-marker: %v", err)
+	}
+	return []*regexp.Regexp{marker}, nil
+}
+
+// Function to detect container starts in the log content using the marker matching most often
+func extractRestarts(content string, markers []*regexp.Regexp) RestartSummary {
+	lines := strings.Split(content, "\n")
+
+	var best RestartSummary
+	for _, marker := range markers {
+		summary := RestartSummary{Marker: marker.String()}
+		for i, line := range lines {
+			if !marker.MatchString(line) {
+				continue
+			}
+			summary.Starts = append(summary.Starts, ContainerStart{
+				Line:      i + 1,
+				Timestamp: parseLineTimestamp(line),
+				Example:   line,
+			})
+		}
+		if len(summary.Starts) > len(best.Starts) {
+			best = summary
+		}
+	}
+	return best
+}
+
+// Function to trim the log to the content after the last detected start, so only the latest run is analyzed.
+// The time window is recomputed from the trimmed content; logs without a restart are returned unchanged.
+func trimToLatestRun(logFile LogFile, markers []*regexp.Regexp) LogFile {
+	restarts := extractRestarts(logFile.Content, markers)
+	if len(restarts.Starts) < 2 {
+		verbosef("No restart found in %s, analyzing the full log", logFile.Path)
+		return logFile
+	}
+
+	last := restarts.Starts[len(restarts.Starts)-1]
+	lines := strings.Split(logFile.Content, "\n")
+	progressf("Analyzing only the latest run of %s: %d of %d lines, after restart %d at line %d\n",
+		logFile.Path, len(lines)-last.Line+1, len(lines), len(restarts.Starts)-1, last.Line)
+
+	logFile.Content = strings.Join(lines[last.Line-1:], "\n")
+	logFile.LineOffset += last.Line - 1
+	logFile.Window = logTimeWindow(logFile.Content)
+	return logFile
+}
+
+// Helper function to parse the first timestamp on a log line, returning the zero time when absent
+func parseLineTimestamp(line string) time.Time {
+	match := lineTimestampRegex.FindString(line)
+	if match == "" {
+		return time.Time{}
+	}
+
+	match = strings.Replace(match, " ", "T", 1)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700", "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, match); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Function to render the restarts as a summary line and a Markdown table of the starts
+func renderRestarts(summary RestartSummary) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Container restarted %d times in this log (%d starts detected by marker `%s`).",
+		len(summary.Starts)-1, len(summary.Starts), summary.Marker))
+
+	// Summarize the restart cadence when the starts are timestamped
+	first, last := summary.Starts[0].Timestamp, summary.Starts[len(summary.Starts)-1].Timestamp
+	if !first.IsZero() && !last.IsZero() && last.After(first) {
+		average := last.Sub(first) / time.Duration(len(summary.Starts)-1)
+		sb.WriteString(fmt.Sprintf(" Average time between starts: %s.", average.Round(time.Second)))
+	}
+
+	sb.WriteString("\n\n| Start | Line | Timestamp | Since Previous | Example |\n")
+	sb.WriteString("|-------|------|-----------|----------------|---------|\n")
+	for i, start := range summary.Starts {
+		timestamp, sincePrevious := "-", "-"
+		if !start.Timestamp.IsZero() {
+			timestamp = start.Timestamp.Format(time.RFC3339)
+			if i > 0 && !summary.Starts[i-1].Timestamp.IsZero() {
+				sincePrevious = start.Timestamp.Sub(summary.Starts[i-1].Timestamp).Round(time.Second).String()
+			}
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %d | %s | %s | `%s` |\n", i+1, start.Line, timestamp, sincePrevious, tableCell(start.Example, 120)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractRestartsWithDefaultMarkers(t *testing.T) {
+	content := `2024-01-02T15:00:00Z Starting ShopApplication using Java 17
+2024-01-02T15:00:05Z Started ShopApplication in 4.8 seconds (process running for 5.2)
+2024-01-02T15:00:05Z Tomcat listening on port 8080
+2024-01-02T15:02:00Z java.lang.OutOfMemoryError: Java heap space
+2024-01-02T15:02:30Z Started ShopApplication in 5.1 seconds (process running for 5.6)
+2024-01-02T15:02:30Z Tomcat listening on port 8080
+2024-01-02T15:05:00Z Started ShopApplication in 4.9 seconds (process running for 5.3)`
+
+	restarts := extractRestarts(content, defaultRestartMarkers)
+	if restarts.Marker != defaultRestartMarkers[0].String() {
+		t.Errorf("marker = %s, want the Spring Boot banner", restarts.Marker)
+	}
+	if len(restarts.Starts) != 3 {
+		t.Fatalf("got %d starts, want 3: %+v", len(restarts.Starts), restarts.Starts)
+	}
+	if restarts.Starts[1].Line != 5 || !restarts.Starts[1].Timestamp.Equal(time.Date(2024, 1, 2, 15, 2, 30, 0, time.UTC)) {
+		t.Errorf("second start = %+v, want line 5 at 15:02:30", restarts.Starts[1])
+	}
+
+	if summary := renderRestarts(restarts); !strings.Contains(summary, "Container restarted 2 times") || !strings.Contains(summary, "Average time between starts: 2m28s.") {
+		t.Errorf("renderRestarts = %q, want 2 restarts about 2m28s apart", summary)
+	}
+}
+
+func TestRestartMarkerFlag(t *testing.T) {
+	markers, err := restartMarkers(`worker \d+ booted`)
+	if err != nil {
+		t.Fatalf("restartMarkers returned error: %v", err)
+	}
+
+	content := "worker 1 booted\nprocessing jobs\nlistening on port 9000\nworker 2 booted\nworker 3 booted"
+	if restarts := extractRestarts(content, markers); len(restarts.Starts) != 3 {
+		t.Errorf("got %d starts with -restart-marker, want 3", len(restarts.Starts))
+	}
+
+	if _, err := restartMarkers(`(unclosed`); err == nil {
+		t.Error("restartMarkers accepted an invalid pattern")
+	}
+}
+
+func TestTrimToLatestRun(t *testing.T) {
+	discardProgress(t)
+	content := "2024-01-02T15:00:00Z server started\n2024-01-02T15:01:00Z panic\n2024-01-02T15:02:00Z server started\n2024-01-02T15:03:00Z ok"
+	logFile := trimToLatestRun(LogFile{Path: "app.log", Content: content, Window: logTimeWindow(content)}, defaultRestartMarkers)
+
+	if logFile.Content != "2024-01-02T15:02:00Z server started\n2024-01-02T15:03:00Z ok" {
+		t.Errorf("content = %q, want the latest run", logFile.Content)
+	}
+	if logFile.LineOffset != 2 {
+		t.Errorf("line offset = %d, want the 2 lines before the latest run", logFile.LineOffset)
+	}
+	if !logFile.Window.Start.Equal(time.Date(2024, 1, 2, 15, 2, 0, 0, time.UTC)) {
+		t.Errorf("window start = %v, want the latest run's start", logFile.Window.Start)
+	}
+}
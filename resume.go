@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// resumeMaxAttempts caps how many times an interrupted stream is continued with -resume
+const resumeMaxAttempts = 3
+
+// resumeTailChars is how much of the partial response is quoted in the continuation prompt
+const resumeTailChars = 200
+
+// resumeStitchMarker marks where a resumed response was stitched together.
+// It is an HTML comment, so it shows in the raw Markdown but not in the rendered output.
+const resumeStitchMarker = "<!-- stream interrupted, resumed here -->"
+
+// StreamInterruptedError reports a stream that ended early, carrying the content received before the interruption
+type StreamInterruptedError struct {
+	Partial ChatResult
+	Err     error
+}
+
+func (e *StreamInterruptedError) Error() string {
+	return e.Err.Error()
+}
+
+// Function to recover from an interrupted stream: with -resume the model is re-prompted to continue
+// from the end of the partial response and the two parts are stitched together, otherwise the partial
+// response is saved to a file so it is not lost
+func resumeStream(ctx context.Context, messages []Message, opts RequestOptions, interrupted *StreamInterruptedError) (ChatResult, error) {
+	partial := interrupted.Partial
+	if opts.Resumes <= 0 || partial.Content == "" {
+		return ChatResult{}, savePartialResponse(interrupted)
+	}
+
+	progressf("\n\nStream interrupted (%v), resuming from the last %d characters...\n", interrupted.Err, resumeTailChars)
+
+	// Ask for the rest of the response, quoting its end so the model knows where to pick up
+	tail := partial.Content
+	if runes := []rune(tail); len(runes) > resumeTailChars {
+		tail = string(runes[len(runes)-resumeTailChars:])
+	}
+	continuation := append(append([]Message{}, messages...),
+		Message{Role: "assistant", Content: partial.Content},
+		Message{Role: "user", Content: fmt.Sprintf("Your previous response was cut off. Continue exactly from where it stopped, without repeating anything or adding an introduction. It ended with:\n\n%s", tail)},
+	)
+
+	// Nested interruptions are resumed again until the attempts run out
+	opts.Resumes--
+	rest, err := sendRequest(ctx, continuation, opts)
+	if err != nil {
+		return ChatResult{}, fmt.Errorf("Error resuming interrupted stream: %v (partial response: %v)", err, savePartialResponse(interrupted))
+	}
+
+	rest.Content = partial.Content + resumeStitchMarker + rest.Content
+	if rest.Model == "" {
+		rest.Model = partial.Model
+	}
+	if rest.SystemFingerprint == "" {
+		rest.SystemFingerprint = partial.SystemFingerprint
+	}
+	return rest, nil
+}
+
+// Function to save the partial content of an interrupted stream to a temporary file,
+// returning the interruption as an error that names the file
+func savePartialResponse(interrupted *StreamInterruptedError) error {
+	if interrupted.Partial.Content == "" {
+		return interrupted.Err
+	}
+
+	file, err := os.CreateTemp("", "k8slogbot-partial-*.md")
+	if err != nil {
+		return fmt.Errorf("%v (could not save the partial response: %v)", interrupted.Err, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(interrupted.Partial.Content); err != nil {
+		return fmt.Errorf("%v (could not save the partial response: %v)", interrupted.Err, err)
+	}
+	return fmt.Errorf("%v; partial response saved to %s (use -resume to continue interrupted streams)", interrupted.Err, file.Name())
+}
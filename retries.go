@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RetryRecord represents a single attempt of a request, appended to the -retries-log as one JSON line
+type RetryRecord struct {
+	Time            time.Time `json:"time"`
+	RequestID       string    `json:"request_id"`                  // Sent as X-Request-ID, shared by every attempt of a request
+	ServerRequestID string    `json:"server_request_id,omitempty"` // X-Request-ID reported back by the gateway, when it differs
+	Attempt         int       `json:"attempt"`
+	StatusCode      int       `json:"status_code,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	DelayMs         int64     `json:"delay_ms"` // Backoff applied before this attempt
+	Outcome         string    `json:"outcome"`  // "success", "timeout", "http_error" or "connection_error"
+	WillRetry       bool      `json:"will_retry"`
+}
+
+// Function to generate a random ID correlating the attempts of a request with gateway logs
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Function to append an attempt record to the retries log.
+// The file is opened per record so concurrent runs can share it and nothing is lost on interruption.
+func appendRetryRecord(path string, record RetryRecord) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Error marshaling retry record: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Error opening retries log %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("Error writing retries log %s: %v", path, err)
+	}
+	return nil
+}
+
+// Failure kinds of a request attempt, each with its own retry limit
+const (
+	failureTimeout    = "timeout"
+	failureHTTPError  = "http_error"
+	failureConnection = "connection_error"
+)
+
+// retryDelay is the pause before the first retry, doubled for each further retry up to retryMaxDelay
+const retryDelay = 2 * time.Second
+
+// retryMaxDelay caps the exponential backoff and the wait requested by a Retry-After header
+const retryMaxDelay = 60 * time.Second
+
+// RetryPolicy holds how many times each kind of failure is retried.
+// Timeouts default to no retries since repeating a huge analysis that timed out is usually wasteful.
+type RetryPolicy struct {
+	TimeoutRetries    int // Timeouts, e.g. dial, TLS handshake or response header timeouts
+	ErrorRetries      int // Retryable HTTP errors (429, 500, 502, 503, 504); other statuses fail immediately
+	ConnectionRetries int // Connection errors, e.g. refused or reset connections
+	MaxRetries        int // Total retries of a request across every kind of failure
+}
+
+// Function to return how many retries the policy allows for a kind of failure
+func (p RetryPolicy) Limit(kind string) int {
+	switch kind {
+	case failureTimeout:
+		return p.TimeoutRetries
+	case failureHTTPError:
+		return p.ErrorRetries
+	case failureConnection:
+		return p.ConnectionRetries
+	}
+	return 0
+}
+
+// Helper function to classify a transport error as a timeout or a connection error
+func classifyRequestError(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return failureTimeout
+	}
+	return failureConnection
+}
+
+// Helper function to check whether an HTTP status is worth retrying
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Function to compute the backoff before a retry (1 for the first): exponential from retryDelay,
+// with jitter so concurrent clients do not retry in lockstep
+func backoffDelay(retry int) time.Duration {
+	backoff := retryMaxDelay
+	if retry < 16 {
+		backoff = retryDelay << uint(retry-1)
+	}
+	if backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+
+	// Wait between half and the full backoff
+	return backoff/2 + time.Duration(mathrand.Int63n(int64(backoff/2)+1))
+}
+
+// Helper function to parse a Retry-After header, given in seconds or as an HTTP date.
+// Returns 0 when the header is absent or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// cancelOnClose releases the context of a streamed attempt once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// Function to POST the request body, retrying failed attempts as allowed by the retry policy.
+// Every attempt shares one X-Request-ID and is appended to the retries log.
+func postWithRetries(ctx context.Context, opts RequestOptions, jsonBody []byte) (*http.Response, error) {
+	// Tag the request so its attempts can be correlated with gateway logs, keeping an ID given with -header
+	requestID := newRequestID()
+	for key, value := range opts.Headers {
+		if http.CanonicalHeaderKey(key) == "X-Request-Id" && value != "" {
+			requestID = value
+		}
+	}
+
+	// Initialize the HTTP client. A streamed response is read long after the client returns,
+	// so streams are bounded by a context deadline instead of the client timeout.
+	client := &http.Client{}
+	if !opts.Stream {
+		client.Timeout = opts.Timeout
+	}
+
+	retries := make(map[string]int)
+	total := 0
+	var delay, retryAfter time.Duration
+	for attempt := 1; ; attempt++ {
+		// Bound the whole stream of this attempt, cancelled when its body is closed
+		attemptCtx, cancel := context.WithCancel(ctx)
+		if opts.Stream && opts.Timeout > 0 {
+			cancel()
+			attemptCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		}
+
+		// Create a new HTTP POST request, as a request body cannot be re-read
+		req, err := http.NewRequestWithContext(attemptCtx, "POST", opts.URL, bytes.NewReader(jsonBody))
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("Error creating HTTP request: %v", err)
+		}
+
+		// Add headers to the request
+		for key, value := range opts.Headers {
+			req.Header.Set(key, value)
+		}
+		req.Header.Set("X-Request-ID", requestID)
+
+		record := RetryRecord{Time: time.Now().UTC(), RequestID: requestID, Attempt: attempt, DelayMs: delay.Milliseconds()}
+
+		// Send the request
+		var kind string
+		var failure error
+		resp, err := client.Do(req)
+		if err != nil && ctx.Err() != nil {
+			// Cancelled by the caller (Ctrl+C), which no retry can recover from
+			cancel()
+			record.Error = err.Error()
+			record.Outcome = "cancelled"
+			logRetryRecord(opts.RetriesLog, record)
+			return nil, fmt.Errorf("Request cancelled (request ID %s): %w", requestID, ctx.Err())
+		}
+		if err != nil {
+			kind = classifyRequestError(err)
+			record.Error = err.Error()
+			failure = fmt.Errorf("Error sending HTTP request (request ID %s): %v", requestID, err)
+		} else {
+			record.StatusCode = resp.StatusCode
+			if serverRequestID := resp.Header.Get("X-Request-ID"); serverRequestID != requestID {
+				record.ServerRequestID = serverRequestID
+			}
+
+			// Check for non-2xx status codes
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				bodyBytes, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				kind = failureHTTPError
+				retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+				failure = fmt.Errorf("Received non-2xx response: %d (request ID %s)\nResponse Body: %s\n", resp.StatusCode, requestID, string(bodyBytes))
+
+				// Client errors will not succeed on a retry
+				if !isRetryableStatus(resp.StatusCode) {
+					cancel()
+					record.Outcome = kind
+					logRetryRecord(opts.RetriesLog, record)
+					return nil, failure
+				}
+			}
+		}
+
+		if failure == nil {
+			record.Outcome = "success"
+			logRetryRecord(opts.RetriesLog, record)
+			resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+		cancel()
+
+		record.Outcome = kind
+		record.WillRetry = retries[kind] < opts.Retry.Limit(kind) && total < opts.Retry.MaxRetries
+		logRetryRecord(opts.RetriesLog, record)
+		if !record.WillRetry {
+			return nil, failure
+		}
+
+		retries[kind]++
+		total++
+
+		// Honor the server's Retry-After (e.g. on 429 or 503) over the computed backoff
+		delay = backoffDelay(total)
+		if retryAfter > 0 {
+			delay = retryAfter
+			if delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+			retryAfter = 0
+		}
+		fmt.Fprintf(os.Stderr, "Warning: attempt %d failed (%s), retrying in %s\n", attempt, kind, delay.Round(time.Millisecond))
+		if err := waitForRetry(ctx, delay); err != nil {
+			return nil, fmt.Errorf("Request cancelled (request ID %s): %w", requestID, err)
+		}
+	}
+}
+
+// waitForRetry pauses before a retry, returning early with the context's error when it is cancelled.
+// It is a variable so tests can observe the delays without sleeping.
+var waitForRetry = func(ctx context.Context, delay time.Duration) error {
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Helper function to record the retry delays instead of sleeping through them
+func recordRetryDelays(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	original := waitForRetry
+	waitForRetry = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { waitForRetry = original })
+	return &delays
+}
+
+// Helper function to serve the given statuses in turn, then 200 with the body "ok"
+func statusSequenceServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			w.Write([]byte("failure"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testRetryOptions(url string) RequestOptions {
+	return RequestOptions{URL: url, Retry: RetryPolicy{ErrorRetries: 3, ConnectionRetries: 3, MaxRetries: 3}}
+}
+
+func TestPostWithRetriesRecoversFromServiceUnavailable(t *testing.T) {
+	recordRetryDelays(t)
+	server, requests := statusSequenceServer(t, nil, http.StatusServiceUnavailable)
+
+	resp, err := postWithRetries(context.Background(), testRetryOptions(server.URL), []byte("{}"))
+	if err != nil {
+		t.Fatalf("postWithRetries returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want %q", body, "ok")
+	}
+	if *requests != 2 {
+		t.Errorf("requests = %d, want 2", *requests)
+	}
+}
+
+func TestPostWithRetriesFailsFastOnClientError(t *testing.T) {
+	delays := recordRetryDelays(t)
+	server, requests := statusSequenceServer(t, nil, http.StatusBadRequest)
+
+	_, err := postWithRetries(context.Background(), testRetryOptions(server.URL), []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("err = %v, want a 400 error", err)
+	}
+	if *requests != 1 {
+		t.Errorf("requests = %d, want 1", *requests)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("delays = %v, want none", *delays)
+	}
+}
+
+func TestPostWithRetriesHonorsRetryAfter(t *testing.T) {
+	delays := recordRetryDelays(t)
+	server, _ := statusSequenceServer(t, http.Header{"Retry-After": {"7"}}, http.StatusTooManyRequests)
+
+	resp, err := postWithRetries(context.Background(), testRetryOptions(server.URL), []byte("{}"))
+	if err != nil {
+		t.Fatalf("postWithRetries returned error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(*delays) != 1 || (*delays)[0] != 7*time.Second {
+		t.Errorf("delays = %v, want [7s]", *delays)
+	}
+}
+
+func TestPostWithRetriesStopsAtTotalCap(t *testing.T) {
+	delays := recordRetryDelays(t)
+	server, requests := statusSequenceServer(t, nil, 502, 502, 502, 502, 502, 502)
+
+	_, err := postWithRetries(context.Background(), testRetryOptions(server.URL), []byte("{}"))
+	if err == nil {
+		t.Fatal("postWithRetries succeeded, want the last 502")
+	}
+	if *requests != 4 {
+		t.Errorf("requests = %d, want 4 (1 attempt + 3 retries)", *requests)
+	}
+	if len(*delays) != 3 {
+		t.Errorf("delays = %v, want 3", *delays)
+	}
+}
+
+func TestBackoffDelayStaysWithinBounds(t *testing.T) {
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{1, retryDelay / 2, retryDelay},
+		{2, retryDelay, 2 * retryDelay},
+		{10, retryMaxDelay / 2, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if delay := backoffDelay(tt.retry); delay < tt.min || delay > tt.max {
+				t.Errorf("backoffDelay(%d) = %s, want between %s and %s", tt.retry, delay, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-30 * time.Second).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// Helper function to serve a response that only completes after a delay, or when the client goes away
+func slowServer(t *testing.T, delay time.Duration, stream bool) *httptest.Server {
+	t.Helper()
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stream {
+			w.Write([]byte("data: {}\n\n"))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(delay):
+			w.Write([]byte("data: [DONE]\n\n"))
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	// Cleanups run last first, so the handler is released before the server waits for it
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+	return server
+}
+
+func TestPostWithRetriesTimesOutSlowServer(t *testing.T) {
+	delays := recordRetryDelays(t)
+	server := slowServer(t, 5*time.Second, false)
+
+	options := testRetryOptions(server.URL)
+	options.Timeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err := postWithRetries(context.Background(), options, []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Fatalf("err = %v, want a client timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, want it cut off by the timeout", elapsed)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("delays = %v, want no retries of a timeout by default", *delays)
+	}
+}
+
+func TestPostWithRetriesTimesOutSlowStream(t *testing.T) {
+	server := slowServer(t, 5*time.Second, true)
+
+	options := testRetryOptions(server.URL)
+	options.Stream = true
+	options.Timeout = 50 * time.Millisecond
+	resp, err := postWithRetries(context.Background(), options, []byte("{}"))
+	if err != nil {
+		t.Fatalf("postWithRetries returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The headers arrived in time, so the deadline cancels the stream while its body is read
+	_, err = ioutil.ReadAll(resp.Body)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the stream cancelled by its deadline", err)
+	}
+}
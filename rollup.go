@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rollupFileName is the name of the batch summary written to -output-dir
+const rollupFileName = "summary.md"
+
+// rollupUnknownGroup collects the logs whose grouping key could not be extracted
+const rollupUnknownGroup = "unknown"
+
+// rollupGroupPatterns maps each -group-by key to the pattern extracting it from a log,
+// matching the labels used for the Loki queries
+var rollupGroupPatterns = map[string]string{
+	"namespace": `namespace (\w[\w\-]*)`,
+	"pod":       `pod (\w[\w\-]*)`,
+}
+
+// rollupSimilarityThreshold is the shingle similarity above which two analyses are near-duplicates
+const rollupSimilarityThreshold = 0.8
+
+// rollupShingleSize is the number of consecutive words in a shingle
+const rollupShingleSize = 3
+
+// RollupEntry represents the outcome of a single log in a batch run
+type RollupEntry struct {
+	LogFile  string
+	Group    string
+	Outputs  []string
+	Findings []string
+	Status   string
+
+	// Key points of the analysis, compared by -dedupe-across-files
+	KeyPoints string
+
+	// Logs whose analysis was collapsed into this entry as near-duplicates
+	Duplicates []string
+}
+
+// Function to validate the -group-by key
+func validateGroupBy(groupBy string) error {
+	if _, ok := rollupGroupPatterns[groupBy]; ok || groupBy == "file" {
+		return nil
+	}
+	return fmt.Errorf("Error: -group-by must be 'namespace', 'pod' or 'file', got %q", groupBy)
+}
+
+// Function to extract the grouping key of a log, bucketing logs without one into "unknown"
+func rollupGroup(groupBy, path, content string) string {
+	if groupBy == "file" {
+		return filepath.Base(path)
+	}
+	if group := extractValue(content, rollupGroupPatterns[groupBy]); group != "" {
+		return group
+	}
+	return rollupUnknownGroup
+}
+
+// Function to build the roll-up entry of a log from its analysis outcome
+func newRollupEntry(groupBy string, logFile LogFile, targets []OutputTarget, report *AnalysisReport, err error) RollupEntry {
+	entry := RollupEntry{
+		LogFile: logFile.Path,
+		Group:   rollupGroup(groupBy, logFile.Path, logFile.Content),
+		Status:  "Analyzed",
+	}
+
+	// Only analyzed logs have outputs to link to
+	switch {
+	case err != nil:
+		entry.Status = fmt.Sprintf("Failed: %v", err)
+	case report == nil:
+		entry.Status = "No new content"
+	default:
+		for _, target := range targets {
+			entry.Outputs = append(entry.Outputs, target.Path)
+		}
+		entry.KeyPoints = report.KeyPoints
+		for _, finding := range report.Findings {
+			entry.Findings = append(entry.Findings, finding.Title)
+		}
+	}
+	return entry
+}
+
+// Function to collapse near-duplicate analyses (e.g. from the pods of one crash-looping deployment)
+// into the first entry with that analysis. Analyses are compared by the Jaccard similarity of the
+// word shingles of their key points; entries without key points are kept as they are.
+func dedupeRollup(entries []RollupEntry) []RollupEntry {
+	var result []RollupEntry
+	var shingles []map[string]bool
+	for _, entry := range entries {
+		if entry.KeyPoints == "" {
+			result = append(result, entry)
+			shingles = append(shingles, nil)
+			continue
+		}
+
+		entryShingles := wordShingles(entry.KeyPoints, rollupShingleSize)
+		duplicate := false
+		for i := range result {
+			if shingles[i] != nil && jaccardSimilarity(shingles[i], entryShingles) >= rollupSimilarityThreshold {
+				result[i].Duplicates = append(result[i].Duplicates, entry.LogFile)
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, entry)
+			shingles = append(shingles, entryShingles)
+		}
+	}
+	return result
+}
+
+// Helper function to build the set of lowercased word shingles of a text
+func wordShingles(text string, size int) map[string]bool {
+	words := strings.Fields(strings.ToLower(text))
+	shingles := make(map[string]bool)
+	if len(words) < size {
+		shingles[strings.Join(words, " ")] = true
+		return shingles
+	}
+	for i := 0; i+size <= len(words); i++ {
+		shingles[strings.Join(words[i:i+size], " ")] = true
+	}
+	return shingles
+}
+
+// Helper function to compute the Jaccard similarity of two shingle sets
+func jaccardSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	intersection := 0
+	for shingle := range a {
+		if b[shingle] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// Function to render the roll-up of a batch run as Markdown, with a table per group.
+// Groups are sorted by name, with "unknown" last.
+func renderRollup(entries []RollupEntry, groupBy, outputDir string) string {
+	groups := make(map[string][]RollupEntry)
+	var names []string
+	for _, entry := range entries {
+		if _, ok := groups[entry.Group]; !ok {
+			names = append(names, entry.Group)
+		}
+		groups[entry.Group] = append(groups[entry.Group], entry)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == rollupUnknownGroup) != (names[j] == rollupUnknownGroup) {
+			return names[j] == rollupUnknownGroup
+		}
+		return names[i] < names[j]
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Batch Summary by %s\n\n", groupBy))
+	sb.WriteString(fmt.Sprintf("%d logs in %d groups.\n", len(entries), len(names)))
+
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", name))
+		sb.WriteString("| Log | Findings | Output | Status |\n")
+		sb.WriteString("|-----|----------|--------|--------|\n")
+		for _, entry := range groups[name] {
+			// Link the outputs relative to the summary so the directory can be moved
+			var links []string
+			for _, output := range entry.Outputs {
+				rel, err := filepath.Rel(outputDir, output)
+				if err != nil {
+					rel = output
+				}
+				links = append(links, fmt.Sprintf("[%s](%s)", filepath.Base(output), filepath.ToSlash(rel)))
+			}
+			// Name the collapsed near-duplicates next to their representative
+			logName := filepath.Base(entry.LogFile)
+			if len(entry.Duplicates) > 0 {
+				var names []string
+				for _, duplicate := range entry.Duplicates {
+					names = append(names, filepath.Base(duplicate))
+				}
+				logName += fmt.Sprintf(" (+%d similar: %s)", len(entry.Duplicates), strings.Join(names, ", "))
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+				logName, valueOrDash(strings.Join(entry.Findings, ", ")), valueOrDash(strings.Join(links, ", ")), strings.ReplaceAll(entry.Status, "|", `\|`)))
+		}
+	}
+	return sb.String()
+}
+
+// Function to write the roll-up of a batch run to the output directory
+func writeRollup(entries []RollupEntry, groupBy, outputDir string) error {
+	path := filepath.Join(outputDir, rollupFileName)
+	if err := ioutil.WriteFile(path, []byte(renderRollup(entries, groupBy, outputDir)), 0644); err != nil {
+		return fmt.Errorf("Error writing to file %s: %v", path, err)
+	}
+	progressf("Batch summary saved to %s\n", path)
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// sarifVersion and sarifSchema identify the SARIF format written with -formats sarif
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLevels are the result levels defined by SARIF
+var sarifLevels = []string{"error", "warning", "note", "none"}
+
+// SARIFRule describes a kind of finding reported by the tool
+type SARIFRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     SARIFMessage       `json:"shortDescription"`
+	DefaultConfiguration SARIFConfiguration `json:"defaultConfiguration"`
+}
+
+// SARIFConfiguration holds the default level of a rule
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFMessage is the text of a rule description or a result
+type SARIFMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+// SARIFResult is a single finding, located in the analyzed log
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+}
+
+// SARIFLocation points to the log file and, when known, the approximate line of a finding
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is the file and region of a finding
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation is the URI of the analyzed log file
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is the line a finding was first seen on
+type SARIFRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// SARIFLog is the top-level SARIF document
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun holds the results of a single analysis and the tool that produced them
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool describes the tool and the rules its results refer to
+type SARIFTool struct {
+	Driver struct {
+		Name  string      `json:"name"`
+		Rules []SARIFRule `json:"rules"`
+	} `json:"driver"`
+}
+
+// findingRules maps each local detector section to its SARIF rule
+var findingRules = map[string]SARIFRule{
+	"Restarts":               newSARIFRule("K8S001", "ContainerRestarts", "Container restarted within the log", "warning"),
+	"Network Issues":         newSARIFRule("K8S002", "NetworkErrors", "DNS, connection or timeout errors", "error"),
+	"TLS/Certificate Issues": newSARIFRule("K8S003", "TLSErrors", "TLS handshake or certificate errors", "error"),
+	"Scheduling Failures":    newSARIFRule("K8S004", "SchedulingFailures", "Pod could not be scheduled", "error"),
+	"Storage Issues":         newSARIFRule("K8S005", "StorageErrors", "Volume mount, attach or PVC errors", "error"),
+	"Admission Denials":      newSARIFRule("K8S006", "AdmissionDenials", "Request denied by an admission webhook", "warning"),
+	"Concurrency Issues":     newSARIFRule("K8S007", "ConcurrencyIssues", "Goroutine dump or deadlock", "error"),
+	"HTTP Status Codes":      newSARIFRule("K8S008", "HTTPStatusCodes", "HTTP status code distribution", "note"),
+	"Configuration Issues":   newSARIFRule("K8S009", "ConfigurationErrors", "Missing environment variable, config file or invalid setting", "error"),
+	"Throttling":             newSARIFRule("K8S010", "Throttling", "Rate limiting or throttling of requests", "warning"),
+	"Reconciliation Issues":  newSARIFRule("K8S011", "ReconciliationErrors", "Operator or Helm release failed to reconcile", "error"),
+}
+
+// rootCauseRule is the rule of the root causes identified by the model with -analyze-json
+var rootCauseRule = newSARIFRule("K8S100", "ModelRootCause", "Root cause identified by the analysis", "warning")
+
+// Helper function to build a SARIF rule
+func newSARIFRule(id, name, description, level string) SARIFRule {
+	return SARIFRule{ID: id, Name: name, ShortDescription: SARIFMessage{Text: description}, DefaultConfiguration: SARIFConfiguration{Level: level}}
+}
+
+// Helper function to map the -analyze-json severity to a SARIF level
+func severityLevel(severity string) string {
+	switch severity {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// Function to render the report as a SARIF log: each local finding and each root cause
+// identified by the model (with -analyze-json) becomes a result located in the log file
+func (r AnalysisReport) SARIF() ([]byte, error) {
+	sarif := SARIFLog{Schema: sarifSchema, Version: sarifVersion, Runs: make([]SARIFRun, 1)}
+	run := &sarif.Runs[0]
+	run.Tool.Driver.Name = "K8sLogbotGoGPT"
+	run.Tool.Driver.Rules = []SARIFRule{}
+	run.Results = []SARIFResult{}
+
+	// Only the rules with results are listed, in order of first use
+	ruleIndex := make(map[string]int)
+	addResult := func(rule SARIFRule, level string, message SARIFMessage, line int) {
+		index, ok := ruleIndex[rule.ID]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[rule.ID] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		location := SARIFLocation{PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: filepath.ToSlash(r.LogFile)}}}
+		if line > 0 {
+			location.PhysicalLocation.Region = &SARIFRegion{StartLine: line}
+		}
+		run.Results = append(run.Results, SARIFResult{
+			RuleID:    rule.ID,
+			RuleIndex: index,
+			Level:     level,
+			Message:   message,
+			Locations: []SARIFLocation{location},
+		})
+	}
+
+	for _, finding := range r.Findings {
+		rule, ok := findingRules[finding.Title]
+		if !ok {
+			rule = newSARIFRule("K8S000", "Finding", finding.Title, "warning")
+		}
+		addResult(rule, rule.DefaultConfiguration.Level,
+			SARIFMessage{Text: fmt.Sprintf("%s detected in the log", finding.Title), Markdown: finding.Body}, finding.Line)
+	}
+
+	if r.Structured != nil {
+		for _, cause := range r.Structured.RootCauses {
+			addResult(rootCauseRule, severityLevel(r.Structured.Severity), SARIFMessage{Text: cause}, 0)
+		}
+	}
+
+	if err := validateSARIF(sarif); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(sarif, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling SARIF report: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Function to check the SARIF log against the shape required by the SARIF schema
+func validateSARIF(sarif SARIFLog) error {
+	var problems []string
+	if sarif.Version != sarifVersion {
+		problems = append(problems, fmt.Sprintf("version must be %s", sarifVersion))
+	}
+	if len(sarif.Runs) == 0 {
+		problems = append(problems, "runs must not be empty")
+	}
+	for i, run := range sarif.Runs {
+		if run.Tool.Driver.Name == "" {
+			problems = append(problems, fmt.Sprintf("runs[%d].tool.driver.name is required", i))
+		}
+		for j, result := range run.Results {
+			path := fmt.Sprintf("runs[%d].results[%d]", i, j)
+			if result.Message.Text == "" {
+				problems = append(problems, path+".message.text is required")
+			}
+			if !containsString(sarifLevels, result.Level) {
+				problems = append(problems, fmt.Sprintf("%s.level %q is not a SARIF level", path, result.Level))
+			}
+			if result.RuleIndex < 0 || result.RuleIndex >= len(run.Tool.Driver.Rules) || run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+				problems = append(problems, fmt.Sprintf("%s.ruleIndex does not point to rule %s", path, result.RuleID))
+			}
+			for _, location := range result.Locations {
+				if location.PhysicalLocation.ArtifactLocation.URI == "" {
+					problems = append(problems, path+" has a location without a uri")
+				}
+				if region := location.PhysicalLocation.Region; region != nil && region.StartLine < 1 {
+					problems = append(problems, path+" has a region with startLine below 1")
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Error: invalid SARIF report: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
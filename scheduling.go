@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SchedulingFailure represents a group of identical scheduler failures for a pod
+type SchedulingFailure struct {
+	Pod       string // Pod that could not be scheduled, when present
+	Available int
+	Total     int
+	Reasons   []SchedulingReason
+	Count     int
+	Example   string
+}
+
+// SchedulingReason represents why a number of nodes were rejected by the scheduler
+type SchedulingReason struct {
+	Nodes    int
+	Category string
+	Reason   string
+}
+
+var (
+	// "0/5 nodes are available: 3 Insufficient cpu, 2 node(s) had taint {...}, that the pod didn't tolerate."
+	schedulingFailureRegex = regexp.MustCompile(`(\d+)/(\d+) nodes are available: (.+)`)
+
+	// "3 Insufficient cpu" or "2 node(s) didn't match Pod's node affinity/selector"
+	schedulingReasonRegex = regexp.MustCompile(`^(\d+)\s+(.+)$`)
+
+	// Pod name from scheduler logs (pod="ns/name", or pod='ns/name' once the log is read) or events (pod/name)
+	schedulingPodRegex = regexp.MustCompile(`\bpod[=/]["']?([a-z0-9][a-z0-9.\-]*(?:/[a-z0-9][a-z0-9.\-]*)?)["']?`)
+)
+
+// schedulingReasonCategories maps scheduler rejection reasons to a category, most specific first
+var schedulingReasonCategories = []struct {
+	category string
+	re       *regexp.Regexp
+}{
+	{"insufficient resources", regexp.MustCompile(`(?i)^Insufficient |Too many pods`)},
+	{"taint", regexp.MustCompile(`(?i)taint`)},
+	{"volume", regexp.MustCompile(`(?i)volume|persistentvolumeclaim|pvc`)},
+	{"affinity/selector", regexp.MustCompile(`(?i)affinity|selector|topology spread`)},
+	{"host port", regexp.MustCompile(`(?i)free ports`)},
+	{"unschedulable node", regexp.MustCompile(`(?i)unschedulable|not ready|unreachable`)},
+	{"preemption", regexp.MustCompile(`(?i)preemption`)},
+}
+
+// Function to extract scheduler failures from the log content, grouped by pod and message
+func extractSchedulingFailures(content string) []SchedulingFailure {
+	var failures []SchedulingFailure
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		matches := schedulingFailureRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		available, _ := strconv.Atoi(matches[1])
+		total, _ := strconv.Atoi(matches[2])
+		reasons := parseSchedulingReasons(matches[3])
+
+		pod := ""
+		if podMatches := schedulingPodRegex.FindStringSubmatch(line); podMatches != nil {
+			pod = podMatches[1]
+		}
+
+		key := pod + "|" + matches[1] + "/" + matches[2] + "|" + fmt.Sprint(reasons)
+		if i, ok := index[key]; ok {
+			failures[i].Count++
+			continue
+		}
+
+		index[key] = len(failures)
+		failures = append(failures, SchedulingFailure{
+			Pod:       pod,
+			Available: available,
+			Total:     total,
+			Reasons:   reasons,
+			Count:     1,
+			Example:   line,
+		})
+	}
+
+	return failures
+}
+
+// Helper function to parse the comma-separated rejection reasons of a scheduler message.
+// The preemption summary appended by newer schedulers is dropped, and commas inside
+// taint braces (e.g. "{key: value}") do not split a reason.
+func parseSchedulingReasons(text string) []SchedulingReason {
+	if i := strings.Index(text, " preemption:"); i >= 0 {
+		text = text[:i]
+	}
+
+	// Strip trailing quoting and punctuation left over from structured log fields
+	text = strings.TrimRight(strings.TrimSpace(text), `."'`)
+
+	var reasons []SchedulingReason
+	for _, part := range splitOutsideBraces(text) {
+		part = strings.TrimSpace(part)
+		matches := schedulingReasonRegex.FindStringSubmatch(part)
+		if matches == nil {
+			// Continuation of the previous reason, e.g. ", that the pod didn't tolerate"
+			if len(reasons) > 0 && part != "" {
+				last := &reasons[len(reasons)-1]
+				last.Reason += ", " + strings.TrimSuffix(part, ".")
+			}
+			continue
+		}
+
+		nodes, _ := strconv.Atoi(matches[1])
+		reason := strings.TrimSuffix(matches[2], ".")
+		reasons = append(reasons, SchedulingReason{Nodes: nodes, Category: categorizeSchedulingReason(reason), Reason: reason})
+	}
+	return reasons
+}
+
+// Helper function to split a string on commas that are not inside braces
+func splitOutsideBraces(text string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range text {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// Helper function to categorize a scheduler rejection reason
+func categorizeSchedulingReason(reason string) string {
+	for _, category := range schedulingReasonCategories {
+		if category.re.MatchString(reason) {
+			return category.category
+		}
+	}
+	return "other"
+}
+
+// Function to render the scheduling failures as a Markdown table with a row per rejection reason
+func renderSchedulingFailures(failures []SchedulingFailure) string {
+	var sb strings.Builder
+	sb.WriteString("| Pod | Nodes Available | Nodes Rejected | Category | Reason | Occurrences |\n")
+	sb.WriteString("|-----|-----------------|----------------|----------|--------|-------------|\n")
+	for _, failure := range failures {
+		available := fmt.Sprintf("%d/%d", failure.Available, failure.Total)
+
+		// Keep failures without parseable reasons visible
+		if len(failure.Reasons) == 0 {
+			sb.WriteString(fmt.Sprintf("| %s | %s | - | - | `%s` | %d |\n",
+				valueOrDash(failure.Pod), available, tableCell(failure.Example, 160), failure.Count))
+			continue
+		}
+
+		for _, reason := range failure.Reasons {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %d | %s | %s | %d |\n",
+				valueOrDash(failure.Pod), available, reason.Nodes, reason.Category, tableCell(reason.Reason, 120), failure.Count))
+		}
+	}
+	return sb.String()
+}
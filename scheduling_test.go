@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractSchedulingFailures(t *testing.T) {
+	content := `I0102 15:04:05 schedule_one.go:1004] "Unable to schedule pod; no fit; waiting" pod='shop/web-7d9f' err='0/5 nodes are available: 3 Insufficient cpu, 2 node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }. preemption: 0/5 nodes are available: 5 Preemption is not helpful for scheduling.'
+I0102 15:04:35 schedule_one.go:1004] "Unable to schedule pod; no fit; waiting" pod="shop/web-7d9f" err="0/5 nodes are available: 3 Insufficient cpu, 2 node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }. preemption: 0/5 nodes are available: 5 Preemption is not helpful for scheduling."
+Warning  FailedScheduling  pod/db-0  0/3 nodes are available: 3 node(s) didn't find available persistent volumes to bind.`
+
+	failures := extractSchedulingFailures(content)
+	if len(failures) != 2 {
+		t.Fatalf("got %d failures, want 2: %+v", len(failures), failures)
+	}
+
+	web := failures[0]
+	if web.Pod != "shop/web-7d9f" || web.Available != 0 || web.Total != 5 || web.Count != 2 {
+		t.Errorf("web failure = %+v, want pod shop/web-7d9f, 0/5, 2 occurrences", web)
+	}
+	wantReasons := []SchedulingReason{
+		{Nodes: 3, Category: "insufficient resources", Reason: "Insufficient cpu"},
+		{Nodes: 2, Category: "taint", Reason: "node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }"},
+	}
+	if !reflect.DeepEqual(web.Reasons, wantReasons) {
+		t.Errorf("web reasons = %+v, want %+v", web.Reasons, wantReasons)
+	}
+
+	db := failures[1]
+	if db.Pod != "db-0" || db.Total != 3 || len(db.Reasons) != 1 || db.Reasons[0].Category != "volume" {
+		t.Errorf("db failure = %+v, want pod db-0 rejected for a volume", db)
+	}
+}
+
+func TestParseSchedulingReasonsJoinsContinuations(t *testing.T) {
+	reasons := parseSchedulingReasons("1 node(s) had taint {dedicated: gpu, zone: a}, that the pod didn't tolerate, 2 node(s) didn't match Pod's node affinity/selector.")
+
+	want := []SchedulingReason{
+		{Nodes: 1, Category: "taint", Reason: "node(s) had taint {dedicated: gpu, zone: a}, that the pod didn't tolerate"},
+		{Nodes: 2, Category: "affinity/selector", Reason: "node(s) didn't match Pod's node affinity/selector"},
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons = %+v, want %+v", reasons, want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SessionRecord is a line of a JSONL -save-session file, holding one message of the conversation
+type SessionRecord struct {
+	Time    time.Time `json:"time"`
+	Turn    int       `json:"turn"` // 0 for the messages seeding the conversation; a retried turn repeats its number
+	Role    string    `json:"role"`
+	Content string    `json:"content"`
+}
+
+// SessionLog appends each exchange of an interactive session to a file as the conversation
+// proceeds, as JSONL when the file ends in .jsonl and as Markdown otherwise
+type SessionLog struct {
+	file       *os.File
+	jsonl      bool
+	redactions []Redaction
+	stripANSI  bool
+}
+
+// Function to open the session file for appending, so a resumed session continues the same record.
+// It returns a nil log, which discards exchanges, when no path is given.
+func openSessionLog(path string, redactions []Redaction, stripMode string) (*SessionLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening session file %s: %v", path, err)
+	}
+	return &SessionLog{
+		file:       file,
+		jsonl:      strings.EqualFold(filepath.Ext(path), ".jsonl"),
+		redactions: redactions,
+		stripANSI:  shouldStripANSI(stripMode, path),
+	}, nil
+}
+
+// Function to record the start of the session: the seeding messages in JSONL, so the session
+// can be loaded again with its full context, or a heading with the log and key points in Markdown
+func (l *SessionLog) Start(messages []Message, logPath, keyPoints string) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if l.jsonl {
+		return l.writeRecords(now, 0, messages...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Chat Session %s\n\n", now.Format(time.RFC3339)))
+	if logPath != "" {
+		sb.WriteString(fmt.Sprintf("**Log**: %s\n\n", logPath))
+	}
+	if keyPoints != "" {
+		sb.WriteString("## Key Points\n\n" + keyPoints + "\n\n")
+	}
+	return l.write(sb.String())
+}
+
+// Function to append an exchange of the session, flushed so a crash does not lose the record.
+// A retried exchange supersedes the previous one of the same turn.
+func (l *SessionLog) Append(turn int, question, answer Message, retry bool) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if l.jsonl {
+		return l.writeRecords(now, turn, question, answer)
+	}
+	heading := fmt.Sprintf("Turn %d", turn)
+	if retry {
+		heading += ", retried"
+	}
+	return l.write(fmt.Sprintf("## %s (%s)\n\n%s\n\n%s\n\n", heading, now.Format(time.RFC3339), quotePrompt(question.Content), answer.Content))
+}
+
+// Helper function to write messages as JSONL records of a turn
+func (l *SessionLog) writeRecords(now time.Time, turn int, messages ...Message) error {
+	var sb strings.Builder
+	for _, message := range messages {
+		data, err := json.Marshal(SessionRecord{Time: now, Turn: turn, Role: message.Role, Content: l.clean(message.Content)})
+		if err != nil {
+			return fmt.Errorf("Error marshaling session record: %v", err)
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+	}
+	return l.flush(sb.String())
+}
+
+// Helper function to write Markdown to the session file
+func (l *SessionLog) write(markdown string) error {
+	return l.flush(l.clean(markdown))
+}
+
+// Helper function to apply the redactions and -strip-ansi to recorded text
+func (l *SessionLog) clean(text string) string {
+	text = redactText(text, l.redactions)
+	if l.stripANSI {
+		text = stripANSI(text)
+	}
+	return text
+}
+
+// Helper function to append text to the session file and flush it to disk
+func (l *SessionLog) flush(text string) error {
+	if _, err := l.file.WriteString(text); err != nil {
+		return fmt.Errorf("Error writing session file %s: %v", l.file.Name(), err)
+	}
+	return l.file.Sync()
+}
+
+// sessionRoles are the message roles a loaded session may contain
+var sessionRoles = map[string]bool{"system": true, "user": true, "assistant": true}
+
+// Function to read the conversation of a JSONL file saved with -save-session. A file shared by several
+// sessions holds each one from its turn 0 records, so the last session is the one continued.
+func loadSession(path string) ([]Message, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading session file %s: %v", path, err)
+	}
+
+	var records []SessionRecord
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var record SessionRecord
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("Error parsing session file %s line %d: %v (expected a JSONL file saved with -save-session)", path, i+1, err)
+		}
+		if !sessionRoles[record.Role] {
+			return nil, fmt.Errorf("Error in session file %s line %d: invalid role %q, expected system, user or assistant", path, i+1, record.Role)
+		}
+
+		// A turn 0 record after the turns of a session starts the next session
+		if record.Turn == 0 && len(records) > 0 && records[len(records)-1].Turn != 0 {
+			records = nil
+		}
+		// A question repeating the turn of the previous records was retried, superseding that exchange
+		if record.Turn > 0 && record.Role == "user" {
+			for len(records) > 0 && records[len(records)-1].Turn == record.Turn {
+				records = records[:len(records)-1]
+			}
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("Error: session file %s contains no messages", path)
+	}
+
+	messages := make([]Message, 0, len(records))
+	for _, record := range records {
+		messages = append(messages, Message{Role: record.Role, Content: record.Content})
+	}
+	return messages, nil
+}
+
+// Function to continue a session loaded with -load-session in interactive mode, with its full context
+func runLoadedSession(ctx context.Context, opts *Options, requestOptions RequestOptions) error {
+	if opts.LogPattern != "" {
+		return fmt.Errorf("Error: -load-session cannot be combined with -log, the loaded session already holds its log context")
+	}
+
+	messages, err := loadSession(opts.LoadSession)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := newRunMetadata(opts, requestOptions.Redactions)
+	if err != nil {
+		return err
+	}
+	defer metadata.Close()
+	defer printCostSummary(metadata)
+
+	progressf("Loaded %d messages from %s\n", len(messages), opts.LoadSession)
+	return newChatSession(opts, requestOptions, metadata, messages).Run(ctx, opts)
+}
+
+// Function to close the session file
+func (l *SessionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetryReplacesLastExchangeInTheSavedSession(t *testing.T) {
+	discardProgress(t)
+	server, _ := chatServer(t, 0)
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	seed := []Message{{Role: "system", Content: "You are a Kubernetes assistant."}}
+	session := newChatSession(&Options{}, RequestOptions{URL: server.URL, Raw: true}, &RunMetadata{}, seed)
+	session.seeded = 1
+	sessionLog, err := openSessionLog(path, nil, "")
+	if err != nil {
+		t.Fatalf("openSessionLog returned error: %v", err)
+	}
+	defer sessionLog.Close()
+	session.sessionLog = sessionLog
+	sessionLog.Start(seed, "", "")
+
+	if err := session.Send(context.Background(), "why?"); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if err := session.retry(context.Background()); err != nil {
+		t.Fatalf("retry returned error: %v", err)
+	}
+	if session.turn != 1 || len(session.history) != 2 || len(session.messages) != 3 {
+		t.Errorf("after a retry: turn %d, %d transcript and %d conversation messages; want 1, 2 and 3", session.turn, len(session.history), len(session.messages))
+	}
+
+	messages, err := loadSession(path)
+	if err != nil {
+		t.Fatalf("loadSession returned error: %v", err)
+	}
+	if len(messages) != 3 || messages[1].Content != "why?" || messages[2].Role != "assistant" {
+		t.Errorf("loaded %+v, want the system prompt and a single exchange", messages)
+	}
+}
+
+func TestLoadSessionSkipsSupersededTurns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	sessionLog, err := openSessionLog(path, nil, "")
+	if err != nil {
+		t.Fatalf("openSessionLog returned error: %v", err)
+	}
+	sessionLog.Start([]Message{{Role: "system", Content: "prompt"}}, "", "")
+	sessionLog.Append(1, Message{Role: "user", Content: "why?"}, Message{Role: "assistant", Content: "first answer"}, false)
+	sessionLog.Append(1, Message{Role: "user", Content: "why?"}, Message{Role: "assistant", Content: "retried answer"}, true)
+	sessionLog.Append(2, Message{Role: "user", Content: "and then?"}, Message{Role: "assistant", Content: "next answer"}, false)
+	sessionLog.Close()
+
+	messages, err := loadSession(path)
+	if err != nil {
+		t.Fatalf("loadSession returned error: %v", err)
+	}
+	var contents []string
+	for _, message := range messages {
+		contents = append(contents, message.Content)
+	}
+	want := []string{"prompt", "why?", "retried answer", "and then?", "next answer"}
+	if len(contents) != len(want) {
+		t.Fatalf("loaded %q, want %q", contents, want)
+	}
+	for i := range want {
+		if contents[i] != want[i] {
+			t.Errorf("message %d = %q, want %q", i, contents[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sinceHeadBytes is the number of leading bytes hashed to detect a rotated log file
+const sinceHeadBytes = 1024
+
+// SinceMarker records how much of a log file was processed by the last successful run
+type SinceMarker struct {
+	Offset    int64     `json:"offset"`
+	HeadHash  string    `json:"head_hash"` // Hash of the leading bytes, used to detect rotation
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Function to load the since file, mapping absolute log paths to their markers.
+// A missing file is treated as an empty state.
+func loadSinceState(sinceFile string) (map[string]SinceMarker, error) {
+	state := make(map[string]SinceMarker)
+
+	data, err := ioutil.ReadFile(sinceFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading since file %s: %v", sinceFile, err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("Error parsing since file %s: %v", sinceFile, err)
+	}
+	return state, nil
+}
+
+// Helper function to hash the leading bytes of a log up to the given offset
+func headHash(data []byte, offset int64) string {
+	n := int64(len(data))
+	if offset < n {
+		n = offset
+	}
+	if n > sinceHeadBytes {
+		n = sinceHeadBytes
+	}
+	sum := sha256.Sum256(data[:n])
+	return hex.EncodeToString(sum[:])
+}
+
+// Function to trim the log content to what was appended since the last successful run.
+// It returns the new content and the marker to save once the run succeeds.
+// When the file shrank or its leading bytes changed, the log was rotated and is read from the start.
+func applySinceFile(sinceFile, path string, data []byte) ([]byte, *SinceMarker, error) {
+	state, err := loadSinceState(sinceFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+
+	var offset int64
+	if previous, ok := state[key]; ok {
+		offset = previous.Offset
+		if offset > int64(len(data)) || headHash(data, offset) != previous.HeadHash {
+			verbosef("Log %s was rotated or truncated, analyzing it from the start", path)
+			offset = 0
+		} else {
+			verbosef("Resuming %s from byte offset %d", path, offset)
+		}
+	}
+
+	marker := &SinceMarker{
+		Offset:   int64(len(data)),
+		HeadHash: headHash(data, int64(len(data))),
+	}
+	return data[offset:], marker, nil
+}
+
+// Function to store the marker for a log after a successful run
+func saveSinceMarker(sinceFile, path string, marker SinceMarker) error {
+	state, err := loadSinceState(sinceFile)
+	if err != nil {
+		return err
+	}
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+
+	marker.UpdatedAt = time.Now().UTC()
+	state[key] = marker
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error marshaling since file: %v", err)
+	}
+
+	if err := ioutil.WriteFile(sinceFile, data, 0644); err != nil {
+		return fmt.Errorf("Error writing since file %s: %v", sinceFile, err)
+	}
+	return nil
+}
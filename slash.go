@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ChatCommand describes a slash command of the interactive session, listed by /help
+type ChatCommand struct {
+	Usage       string
+	Description string
+}
+
+// chatCommands lists the slash commands understood by the interactive session
+var chatCommands = []ChatCommand{
+	{"/save <file>", "Save the transcript of the session so far as Markdown"},
+	{"/clear", "Reset the conversation to the system prompt, dropping the log context and previous turns"},
+	{"/model <name>", "Switch the model used for the following turns"},
+	{"/retry", "Resend the last question, replacing its answer"},
+	{"/help", "List these commands"},
+}
+
+// A slash command: a single word after the slash, so paths such as /var/log are still sent as messages
+var chatCommandRegex = regexp.MustCompile(`^/([a-zA-Z]+)(?:\s+(.*))?$`)
+
+// Function to run a slash command typed in the session instead of sending it to the model.
+// It returns false when the input is not a command; the error is that of a resent question.
+func (s *ChatSession) handleSlashCommand(ctx context.Context, userInput string) (bool, error) {
+	match := chatCommandRegex.FindStringSubmatch(strings.TrimSpace(userInput))
+	if match == nil {
+		return false, nil
+	}
+	argument := strings.TrimSpace(match[2])
+
+	switch strings.ToLower(match[1]) {
+	case "save":
+		if argument == "" {
+			fmt.Fprintln(os.Stderr, "Usage: /save <file>")
+			return true, nil
+		}
+		if err := s.writeTranscript(argument); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	case "clear":
+		s.clear()
+		progressf("Conversation cleared, only the system prompt is kept.\n")
+	case "model":
+		if argument == "" {
+			progressf("Current model: %s\n", s.requestOptions.Model)
+			return true, nil
+		}
+		s.requestOptions.Model = argument
+		progressf("Switched to model %s.\n", argument)
+	case "retry":
+		return true, s.retry(ctx)
+	case "help":
+		progressf("Commands:\n")
+		for _, command := range chatCommands {
+			progressf("  %-15s %s\n", command.Usage, command.Description)
+		}
+		progressf("  %-15s %s\n", "exit", "End the session")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command /%s, type /help to list the commands\n", match[1])
+	}
+	return true, nil
+}
+
+// Function to reset the conversation to the system prompt. The transcript keeps every turn.
+func (s *ChatSession) clear() {
+	var kept []Message
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		kept = s.messages[:1:1]
+	}
+	s.messages = kept
+	s.seeded = len(kept)
+	s.suggestions = nil
+}
+
+// Function to resend the last question of the conversation, replacing its answer without counting a new turn.
+// The previous exchange is kept when the new request fails or is cancelled.
+func (s *ChatSession) retry(ctx context.Context) error {
+	last := -1
+	for i := len(s.messages) - 1; i >= s.seeded; i-- {
+		if s.messages[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to retry yet")
+		return nil
+	}
+
+	previous := s.messages
+	question := s.messages[last].Content
+	s.messages = s.messages[:last:last]
+	if !s.echoPrompt {
+		progressf("> %s\n", question)
+	}
+
+	// As in SendTurn, Ctrl+C cancels the response and the session goes on
+	turnCtx, stop := turnContext(ctx)
+	defer stop()
+	if err := s.send(turnCtx, question, true); err != nil {
+		s.messages = previous
+		if turnCtx.Err() != nil && ctx.Err() == nil {
+			progressf("\nResponse cancelled.\n")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
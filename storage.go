@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// StorageIssue represents a group of identical volume mount or PVC errors
+type StorageIssue struct {
+	Kind    string
+	Volume  string // Volume or PVC name, when present
+	Reason  string
+	Count   int
+	Example string
+}
+
+// storageErrorKinds maps kubelet and controller storage errors to a kind, most specific first.
+// The optional "volume" and "reason" groups capture the volume name and the failure reason.
+// Quotes may be double or single, as double quotes are replaced when the log is read.
+var storageErrorKinds = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"multi-attach", regexp.MustCompile(`Multi-Attach error for volume ["'](?P<volume>[^"']+)["']\s*(?P<reason>.*)`)},
+	{"attach failed", regexp.MustCompile(`AttachVolume\.Attach failed for volume ["'](?P<volume>[^"']+)["']\s*:?\s*(?P<reason>.*)`)},
+	{"mount failed", regexp.MustCompile(`MountVolume\.\w+ failed for volume ["'](?P<volume>[^"']+)["']\s*:?\s*(?P<reason>.*)`)},
+	{"mount timeout", regexp.MustCompile(`Unable to (?:attach or )?mount volumes: unmounted volumes=\[(?P<volume>[^\]]*)\].*?:\s*(?P<reason>timed out waiting for the condition|context deadline exceeded)`)},
+	{"PVC not found", regexp.MustCompile(`persistentvolumeclaim ["'](?P<volume>[^"']+)["'] not found`)},
+	{"unbound PVC", regexp.MustCompile(`(?P<reason>pod has unbound (?:immediate )?PersistentVolumeClaims)`)},
+	{"provisioning failed", regexp.MustCompile(`failed to provision volume with StorageClass ["'](?P<volume>[^"']+)["']\s*:?\s*(?P<reason>.*)`)},
+}
+
+// Function to extract storage errors from the log content, grouped by kind, volume and reason
+func extractStorageIssues(content string) []StorageIssue {
+	var issues []StorageIssue
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		for _, errorKind := range storageErrorKinds {
+			matches := errorKind.re.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+
+			var volume, reason string
+			for i, name := range errorKind.re.SubexpNames() {
+				switch name {
+				case "volume":
+					volume = strings.TrimSpace(matches[i])
+				case "reason":
+					reason = strings.TrimSpace(strings.TrimRight(matches[i], `"'`))
+				}
+			}
+
+			key := errorKind.kind + "|" + volume + "|" + reason
+			if i, ok := index[key]; ok {
+				issues[i].Count++
+			} else {
+				index[key] = len(issues)
+				issues = append(issues, StorageIssue{Kind: errorKind.kind, Volume: volume, Reason: reason, Count: 1, Example: line})
+			}
+			break
+		}
+	}
+
+	return issues
+}
+
+// Function to render the storage issues as a Markdown table
+func renderStorageIssues(issues []StorageIssue) string {
+	var sb strings.Builder
+	sb.WriteString("| Kind | Volume/PVC | Reason | Occurrences | Example |\n")
+	sb.WriteString("|------|------------|--------|-------------|---------|\n")
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | `%s` |\n",
+			issue.Kind, tableCell(valueOrDash(issue.Volume), 60), tableCell(valueOrDash(issue.Reason), 120),
+			issue.Count, tableCell(issue.Example, 160)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractStorageIssues(t *testing.T) {
+	content := `Warning  FailedAttachVolume  Multi-Attach error for volume 'pvc-3f2a' Volume is already used by pod(s) db-0
+Warning  FailedMount  MountVolume.SetUp failed for volume 'config' : configmap 'app-config' not found
+Warning  FailedMount  MountVolume.SetUp failed for volume 'config' : configmap 'app-config' not found
+Warning  FailedMount  Unable to attach or mount volumes: unmounted volumes=[data], unattached volumes=[data kube-api-access]: timed out waiting for the condition
+Warning  FailedScheduling  persistentvolumeclaim 'data-db-0' not found
+Warning  FailedScheduling  0/3 nodes are available: pod has unbound immediate PersistentVolumeClaims.
+Warning  ProvisioningFailed  failed to provision volume with StorageClass 'fast-ssd': rpc error: code = ResourceExhausted desc = quota exceeded`
+
+	issues := extractStorageIssues(content)
+	for i := range issues {
+		issues[i].Example = "" // Only the grouping is checked here
+	}
+	want := []StorageIssue{
+		{Kind: "multi-attach", Volume: "pvc-3f2a", Reason: "Volume is already used by pod(s) db-0", Count: 1},
+		{Kind: "mount failed", Volume: "config", Reason: "configmap 'app-config' not found", Count: 2},
+		{Kind: "mount timeout", Volume: "data", Reason: "timed out waiting for the condition", Count: 1},
+		{Kind: "PVC not found", Volume: "data-db-0", Count: 1},
+		{Kind: "unbound PVC", Reason: "pod has unbound immediate PersistentVolumeClaims", Count: 1},
+		{Kind: "provisioning failed", Volume: "fast-ssd", Reason: "rpc error: code = ResourceExhausted desc = quota exceeded", Count: 1},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("extractStorageIssues =\n%+v\nwant\n%+v", issues, want)
+	}
+}
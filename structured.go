@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// analysisSchemaJSON is the JSON Schema every -analyze-json result is validated against
+//
+//go:embed analysis.schema.json
+var analysisSchemaJSON []byte
+
+// StructuredAnalysis represents the analysis returned by -analyze-json
+type StructuredAnalysis struct {
+	Summary           string             `json:"summary"`
+	Severity          string             `json:"severity"`
+	RootCauses        []string           `json:"rootCauses"`
+	Recommendations   []string           `json:"recommendations"`
+	AffectedResources []AffectedResource `json:"affectedResources"`
+}
+
+// AffectedResource represents a Kubernetes resource affected by the issues in the log
+type AffectedResource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// JSONSchema represents the subset of JSON Schema used by the embedded analysis schema
+type JSONSchema struct {
+	Type       string                 `json:"type"`
+	Required   []string               `json:"required"`
+	Properties map[string]*JSONSchema `json:"properties"`
+	Items      *JSONSchema            `json:"items"`
+	Enum       []string               `json:"enum"`
+	MinItems   int                    `json:"minItems"`
+	MinLength  int                    `json:"minLength"`
+}
+
+// Matches a response wrapped in a ```json code fence
+var jsonFenceRegex = regexp.MustCompile("(?s)^```(?:json)?\\s*(.*?)\\s*```$")
+
+// Function to build the prompt asking for the analysis as JSON conforming to the schema
+func structuredAnalysisPrompt() string {
+	return "Respond only with a single JSON object, without Markdown or any other text, that conforms to this JSON Schema:\n\n" +
+		string(analysisSchemaJSON) +
+		"\nUse severity to rate the overall impact, list the root causes and actionable recommendations as plain sentences, " +
+		"and list every Kubernetes resource (kind, name and namespace when known) affected by the issues."
+}
+
+// Function to parse a -analyze-json response and validate it against the embedded schema.
+// It returns the analysis and, when invalid, a description of each violation.
+func parseStructuredAnalysis(response string) (StructuredAnalysis, []string, error) {
+	var schema JSONSchema
+	if err := json.Unmarshal(analysisSchemaJSON, &schema); err != nil {
+		return StructuredAnalysis{}, nil, fmt.Errorf("Error parsing embedded analysis schema: %v", err)
+	}
+
+	// Models often wrap JSON in a code fence despite being asked not to
+	content := strings.TrimSpace(response)
+	if matches := jsonFenceRegex.FindStringSubmatch(content); matches != nil {
+		content = matches[1]
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return StructuredAnalysis{}, []string{fmt.Sprintf("the response is not valid JSON: %v", err)}, nil
+	}
+	if violations := schema.Validate(value, "$"); len(violations) > 0 {
+		return StructuredAnalysis{}, violations, nil
+	}
+
+	var analysis StructuredAnalysis
+	if err := json.Unmarshal([]byte(content), &analysis); err != nil {
+		return StructuredAnalysis{}, []string{fmt.Sprintf("the response does not match the analysis structure: %v", err)}, nil
+	}
+	return analysis, nil, nil
+}
+
+// Function to validate a decoded JSON value against the schema, returning a description of each violation
+func (s *JSONSchema) Validate(value interface{}, path string) []string {
+	var violations []string
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an object", path)}
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+
+		// Validate in a stable order so the violations are reproducible
+		var names []string
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := object[name]; ok {
+				violations = append(violations, s.Properties[name].Validate(property, path+"."+name)...)
+			}
+		}
+
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an array", path)}
+		}
+		if len(array) < s.MinItems {
+			violations = append(violations, fmt.Sprintf("%s must have at least %d items", path, s.MinItems))
+		}
+		if s.Items != nil {
+			for i, item := range array {
+				violations = append(violations, s.Items.Validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s must be a string", path)}
+		}
+		if len(strings.TrimSpace(str)) < s.MinLength {
+			violations = append(violations, fmt.Sprintf("%s must not be empty", path))
+		}
+		if len(s.Enum) > 0 && !containsString(s.Enum, str) {
+			violations = append(violations, fmt.Sprintf("%s must be one of %s, got %q", path, strings.Join(s.Enum, ", "), str))
+		}
+	}
+
+	return violations
+}
+
+// Helper function to check whether a list contains a string
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to render the structured analysis as Markdown for the Markdown and HTML outputs
+func renderStructuredAnalysis(analysis StructuredAnalysis) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**Severity**: %s\n\n", analysis.Severity))
+	sb.WriteString(analysis.Summary + "\n\n")
+
+	sb.WriteString("## Root Causes\n\n")
+	if len(analysis.RootCauses) == 0 {
+		sb.WriteString("No root causes identified.\n")
+	}
+	for _, cause := range analysis.RootCauses {
+		sb.WriteString("- " + cause + "\n")
+	}
+
+	sb.WriteString("\n## Recommendations\n\n")
+	for i, recommendation := range analysis.Recommendations {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, recommendation))
+	}
+
+	if len(analysis.AffectedResources) > 0 {
+		sb.WriteString("\n## Affected Resources\n\n")
+		sb.WriteString("| Kind | Name | Namespace |\n")
+		sb.WriteString("|------|------|-----------|\n")
+		for _, resource := range analysis.AffectedResources {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n",
+				tableCell(resource.Kind, 40), tableCell(resource.Name, 80), valueOrDash(resource.Namespace)))
+		}
+	}
+	return sb.String()
+}
+
+// Function to send the analysis request for -analyze-json, re-prompting once when the response violates the schema
+func requestStructuredAnalysis(ctx context.Context, messages []Message, requestOptions RequestOptions, metadata *RunMetadata) (StructuredAnalysis, error) {
+	// Ask for the schema in the final user message
+	messages = append([]Message(nil), messages...)
+	last := &messages[len(messages)-1]
+	last.Content += "\n\n" + structuredAnalysisPrompt()
+
+	result, err := sendRequest(ctx, messages, requestOptions)
+	if err != nil {
+		return StructuredAnalysis{}, err
+	}
+	metadata.Record("Analysis (JSON)", requestOptions.Model, result)
+
+	analysis, violations, err := parseStructuredAnalysis(result.Content)
+	if err != nil || len(violations) == 0 {
+		return analysis, err
+	}
+	verbosef("JSON analysis failed schema validation: %s", strings.Join(violations, "; "))
+
+	messages = append(messages,
+		Message{Role: "assistant", Content: result.Content},
+		Message{Role: "user", Content: buildSchemaRetryPrompt(violations)},
+	)
+	retryResult, err := sendRequest(ctx, messages, requestOptions)
+	if err != nil {
+		return StructuredAnalysis{}, err
+	}
+	metadata.Record("Analysis (JSON validation retry)", requestOptions.Model, retryResult)
+
+	analysis, violations, err = parseStructuredAnalysis(retryResult.Content)
+	if err != nil {
+		return StructuredAnalysis{}, err
+	}
+	if len(violations) > 0 {
+		return StructuredAnalysis{}, fmt.Errorf("Error: JSON analysis still fails schema validation after re-prompt: %s", strings.Join(violations, "; "))
+	}
+	verbosef("JSON analysis passed schema validation after re-prompt")
+	return analysis, nil
+}
+
+// Helper function to build the follow-up prompt asking the model to fix the schema violations
+func buildSchemaRetryPrompt(violations []string) string {
+	var sb strings.Builder
+	sb.WriteString("Your previous response does not conform to the required JSON Schema:\n\n")
+	for _, violation := range violations {
+		sb.WriteString(fmt.Sprintf("- %s\n", violation))
+	}
+	sb.WriteString("\nRespond again with only the corrected JSON object, keeping all of its content.")
+	return sb.String()
+}
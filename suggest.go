@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// suggestCount is how many follow-up questions are proposed after each response
+const suggestCount = 3
+
+// suggestPrompt asks for follow-up questions in a format that is easy to parse
+var suggestPrompt = fmt.Sprintf(`Based on the question and answer above, propose exactly %d short follow-up questions a Kubernetes operator could ask next to continue the investigation.
+Write one question per line, without numbering or any other text.`, suggestCount)
+
+// suggestionPrefixRegex matches list markers the model may add despite the instructions
+var suggestionPrefixRegex = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*•])\s*`)
+
+// Function to ask for follow-up questions about the last exchange and list them as numbered options.
+// Only the last question and answer are sent, so the call stays cheap; failures only skip the suggestions.
+func (s *ChatSession) suggestFollowUps(ctx context.Context) {
+	s.suggestions = nil
+	if len(s.messages) < 2 {
+		return
+	}
+
+	messages := append([]Message{}, s.messages[len(s.messages)-2:]...)
+	messages = append(messages, Message{Role: "user", Content: suggestPrompt})
+	result, err := sendSilently(ctx, messages, s.requestOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not suggest follow-up questions: %v\n", err)
+		return
+	}
+	s.metadata.Record(fmt.Sprintf("Follow-up Suggestions %d", s.turn), s.requestOptions.Model, result)
+
+	s.suggestions = parseSuggestions(result.Content)
+	if len(s.suggestions) == 0 {
+		return
+	}
+	progressf("\nSuggested follow-ups (type a number to ask):\n")
+	for i, suggestion := range s.suggestions {
+		progressf("  %d. %s\n", i+1, suggestion)
+	}
+}
+
+// Function to parse the suggested questions out of the response, one per line
+func parseSuggestions(content string) []string {
+	var suggestions []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(suggestionPrefixRegex.ReplaceAllString(line, ""))
+		line = strings.Trim(line, "*_")
+		if line == "" {
+			continue
+		}
+		suggestions = append(suggestions, line)
+		if len(suggestions) == suggestCount {
+			break
+		}
+	}
+	return suggestions
+}
+
+// Function to resolve a typed number to the suggested question it stands for, or "" if it is not one
+func (s *ChatSession) pickSuggestion(userInput string) string {
+	choice, err := strconv.Atoi(strings.TrimSpace(userInput))
+	if err != nil || choice < 1 || choice > len(s.suggestions) {
+		return ""
+	}
+	return s.suggestions[choice-1]
+}
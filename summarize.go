@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// summaryPrefix marks the message holding the condensed earlier conversation
+const summaryPrefix = "Conversation so far (summary of earlier turns):\n\n"
+
+// summarizePrompt asks for a summary of older turns that keeps what later questions may rely on
+const summarizePrompt = `Summarize the following part of a Kubernetes log troubleshooting conversation.
+Keep every fact, error message, resource name, command, hypothesis and decision that a later question may rely on.
+Drop pleasantries and repetition. Answer with the summary only.
+
+`
+
+// Function to condense the oldest half of the conversation into a single summary message
+// once the history exceeds the token threshold. A previous summary is part of the oldest
+// half, so the summary is rebuilt recursively as the session grows.
+func (s *ChatSession) summarizeHistory(ctx context.Context) error {
+	if s.summarizeTokens <= 0 || estimateMessagesTokens(s.messages) <= s.summarizeTokens {
+		return nil
+	}
+
+	// Summarize whole question and answer pairs, keeping the seeded context and the latest turns intact
+	turns := s.messages[s.seeded:]
+	count := len(turns) / 2
+	count -= count % 2
+	if count < 2 {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(summarizePrompt)
+	for _, message := range turns[:count] {
+		sb.WriteString(fmt.Sprintf("%s: %s\n\n", message.Role, message.Content))
+	}
+
+	// Summarize silently: the summary is not shown, only the notice that it happened
+	result, err := sendSilently(ctx, []Message{{Role: "user", Content: sb.String()}}, s.requestOptions)
+	if err != nil {
+		return fmt.Errorf("Error summarizing the conversation: %v", err)
+	}
+	s.summaries++
+	s.metadata.Record(fmt.Sprintf("Conversation Summary %d", s.summaries), s.requestOptions.Model, result)
+
+	messages := append([]Message{}, s.messages[:s.seeded]...)
+	messages = append(messages, Message{Role: "user", Content: summaryPrefix + strings.TrimSpace(result.Content)})
+	messages = append(messages, Message{Role: "assistant", Content: "Understood, I will keep this earlier context in mind."})
+	s.messages = append(messages, turns[count:]...)
+
+	progressf("\n(Earlier conversation condensed into a summary to stay under %d tokens.)\n", s.summarizeTokens)
+	return nil
+}
+
+// Function to drop the oldest messages once the history exceeds -max-history-tokens, so the conversation
+// stays within the context window. The system prompt and the latest message are never dropped,
+// and the history always resumes on a user message. Summarizing with -auto-summarize runs first.
+// The messages are trimmed into a new slice, returned with the number dropped, so the session only
+// commits the trim with commitTrim once the request succeeds and a failed turn can be retried in full.
+func (s *ChatSession) trimHistory(messages []Message) ([]Message, int) {
+	if s.maxHistoryTokens <= 0 || estimateMessagesTokens(messages) <= s.maxHistoryTokens {
+		return messages, 0
+	}
+
+	start := 0
+	if len(messages) > 0 && messages[0].Role == "system" {
+		start = 1
+	}
+	kept := messages[start:]
+	for len(kept) > 1 && estimateMessagesTokens(messages[:start])+estimateMessagesTokens(kept) > s.maxHistoryTokens {
+		kept = kept[1:]
+	}
+	for len(kept) > 1 && kept[0].Role != "user" {
+		kept = kept[1:]
+	}
+
+	dropped := len(messages) - start - len(kept)
+	if dropped == 0 {
+		return messages, 0
+	}
+	trimmed := append([]Message{}, messages[:start]...)
+	return append(trimmed, kept...), dropped
+}
+
+// Function to account for the messages dropped by trimHistory once the trimmed history is kept
+func (s *ChatSession) commitTrim(dropped int) {
+	if dropped == 0 {
+		return
+	}
+
+	start := 0
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		start = 1
+	}
+	s.seeded -= dropped
+	if s.seeded < start {
+		s.seeded = start
+	}
+
+	progressf("\n(Dropped the %d oldest messages to stay under %d tokens.)\n", dropped, s.maxHistoryTokens)
+}
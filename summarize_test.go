@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Helper function to serve chat completions, failing the first failures requests with a 400.
+// The number of messages of each request is recorded.
+func chatServer(t *testing.T, failures int) (*httptest.Server, *[]int) {
+	t.Helper()
+	var sent []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body RequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, len(body.Messages))
+		if len(sent) <= failures {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"answer"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &sent
+}
+
+func TestSendKeepsHistoryUntilTrimmedRequestSucceeds(t *testing.T) {
+	discardProgress(t)
+	recordRetryDelays(t)
+	server, sent := chatServer(t, 1)
+
+	long := strings.Repeat("pod web-1 restarted ", 50)
+	messages := []Message{
+		{Role: "system", Content: "You are a Kubernetes assistant."},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+	}
+	opts := &Options{MaxHistoryTokens: estimateMessagesTokens(messages[:3]) + 50}
+	session := newChatSession(opts, RequestOptions{URL: server.URL, Raw: true}, &RunMetadata{}, messages)
+	session.seeded = 1
+
+	// The failed request was trimmed, but the conversation keeps every message for /retry
+	if err := session.Send(context.Background(), "why?"); err == nil {
+		t.Fatal("Send succeeded, want the 400 error")
+	}
+	if len(session.messages) != 5 || session.seeded != 1 {
+		t.Fatalf("after a failed turn: %d messages, seeded %d; want 5 and 1", len(session.messages), session.seeded)
+	}
+
+	if err := session.Send(context.Background(), "why?"); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if (*sent)[0] != (*sent)[1] || (*sent)[0] >= 6 {
+		t.Errorf("messages sent = %v, want the same trimmed history both times", *sent)
+	}
+	if len(session.messages) != (*sent)[1]+1 || session.messages[0].Role != "system" {
+		t.Errorf("after a successful turn: %d messages, want the %d sent plus the answer, starting with the system prompt", len(session.messages), (*sent)[1])
+	}
+	if last := session.messages[len(session.messages)-1]; last.Content != "answer" {
+		t.Errorf("last message = %+v, want the answer", last)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ThrottlingIssue represents a group of throttling events against the same resource or endpoint
+type ThrottlingIssue struct {
+	Kind     string // client-side throttling, HTTP 429, rate limit exceeded, throttled
+	Resource string // Endpoint, API operation or resource, when present
+	Count    int
+	Example  string
+}
+
+// throttlingKinds maps throttling messages to a kind, most specific first.
+// The optional "resource" group captures the throttled endpoint.
+var throttlingKinds = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"client-side throttling", regexp.MustCompile(`(?:Waited for [\d.]+\w*s due to client-side throttling|Throttling request took [\d.]+\w*s).*?request: \w+:(?P<resource>\S+)`)},
+	{"HTTP 429", regexp.MustCompile(`(?i:\b429\b\s*\(?Too Many Requests|Too Many Requests\b)|\b(?:status|code|status_code|statusCode)["']?\s*[=:]\s*["']?429\b`)},
+	{"throttled", regexp.MustCompile(`ThrottlingException|TooManyRequestsException|\bSlowDown\b`)},
+	{"rate limit exceeded", regexp.MustCompile(`(?i:rate[ _-]?limit(?:ed|ing)?\s*(?:was |has been )?(?:exceeded|reached|hit)|\brate exceeded|quota exceeded|RequestLimitExceeded|rateLimitExceeded)`)},
+	{"throttled", regexp.MustCompile(`(?i:\bthrottled\b|\bthrottling\b)`)},
+}
+
+// throttledResourcePatterns extract the throttled endpoint or resource, in order of preference
+var throttledResourcePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`['"]?(https?://[^\s'"]+)`),
+	regexp.MustCompile(`\b(?:GET|POST|PUT|PATCH|DELETE|HEAD) (/[^\s'"]*)`),
+	regexp.MustCompile(`when calling the (\w+) operation`), // AWS SDKs, before "operation: <message>" is taken for a key
+	regexp.MustCompile(`(?i)\b(?:endpoint|resource|operation|api|path|url|method)["']?\s*[=:]\s*["']?([^\s'",]+)`),
+}
+
+// Function to extract throttling events from the log content, grouped by kind and resource.
+// Access log lines with a 429 status count as well, using the same patterns as the HTTP status tally.
+func extractThrottlingIssues(content string, accessLogPatterns []*regexp.Regexp) []ThrottlingIssue {
+	var issues []ThrottlingIssue
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		kind, resource := classifyThrottling(line)
+		if kind == "" {
+			if accessLogStatus(line, accessLogPatterns) != 429 {
+				continue
+			}
+			kind = "HTTP 429"
+		}
+		if resource == "" {
+			resource = extractThrottledResource(line)
+		}
+		resource = trimQuery(resource)
+
+		key := kind + "|" + resource
+		if i, ok := index[key]; ok {
+			issues[i].Count++
+		} else {
+			index[key] = len(issues)
+			issues = append(issues, ThrottlingIssue{Kind: kind, Resource: resource, Count: 1, Example: line})
+		}
+	}
+
+	return issues
+}
+
+// Helper function to find the throttling kind of a line and the resource captured by its pattern
+func classifyThrottling(line string) (string, string) {
+	for _, throttlingKind := range throttlingKinds {
+		matches := throttlingKind.re.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		if i := throttlingKind.re.SubexpIndex("resource"); i > 0 {
+			return throttlingKind.kind, matches[i]
+		}
+		return throttlingKind.kind, ""
+	}
+	return "", ""
+}
+
+// Helper function to extract the throttled endpoint from a line
+func extractThrottledResource(line string) string {
+	for _, re := range throttledResourcePatterns {
+		if matches := re.FindStringSubmatch(line); matches != nil {
+			return strings.TrimRight(matches[1], ".,;:)]}")
+		}
+	}
+	return ""
+}
+
+// Helper function to drop the query string of an endpoint so calls to it are grouped together
+func trimQuery(resource string) string {
+	if i := strings.Index(resource, "?"); i >= 0 {
+		return resource[:i]
+	}
+	return resource
+}
+
+// Function to render the throttling events as a Markdown table with the total count
+func renderThrottlingIssues(issues []ThrottlingIssue) string {
+	total := 0
+	for _, issue := range issues {
+		total += issue.Count
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d throttling events. Consider client backoff with jitter, lower request rates, higher quotas or scaling the throttled dependency.\n\n", total))
+	sb.WriteString("| Kind | Resource/Endpoint | Occurrences | Example |\n")
+	sb.WriteString("|------|-------------------|-------------|---------|\n")
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | `%s` |\n",
+			issue.Kind, tableCell(valueOrDash(issue.Resource), 80), issue.Count, tableCell(issue.Example, 160)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractThrottlingIssues(t *testing.T) {
+	content := `I0102 15:04:05.123 request.go:697] Waited for 1.19s due to client-side throttling, not priority and fairness, request: GET:https://10.96.0.1:443/api/v1/namespaces/shop/pods?limit=500
+I0102 15:04:07.456 request.go:697] Waited for 2.01s due to client-side throttling, not priority and fairness, request: GET:https://10.96.0.1:443/api/v1/namespaces/shop/pods?limit=100
+ERROR payment failed: 429 Too Many Requests from https://api.stripe.com/v1/charges
+botocore.exceptions.ClientError: An error occurred (ThrottlingException) when calling the PutItem operation: Rate exceeded
+WARN upstream rate limit exceeded endpoint=/v1/search
+10.0.0.1 - - [02/Jan/2024:15:04:05 +0000] 'POST /api/orders HTTP/1.1' 429 0
+INFO request completed status=200`
+
+	issues := extractThrottlingIssues(content, defaultAccessLogPatterns)
+	for i := range issues {
+		issues[i].Example = "" // Only the grouping is checked here
+	}
+	want := []ThrottlingIssue{
+		{Kind: "client-side throttling", Resource: "https://10.96.0.1:443/api/v1/namespaces/shop/pods", Count: 2},
+		{Kind: "HTTP 429", Resource: "https://api.stripe.com/v1/charges", Count: 1},
+		{Kind: "throttled", Resource: "PutItem", Count: 1},
+		{Kind: "rate limit exceeded", Resource: "/v1/search", Count: 1},
+		{Kind: "HTTP 429", Resource: "/api/orders", Count: 1},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("extractThrottlingIssues =\n%+v\nwant\n%+v", issues, want)
+	}
+}
+
+func TestRenderThrottlingIssuesCountsEvents(t *testing.T) {
+	issues := []ThrottlingIssue{{Kind: "HTTP 429", Resource: "/api", Count: 3, Example: "429"}, {Kind: "throttled", Count: 2, Example: "throttled"}}
+	if rendered := renderThrottlingIssues(issues); !strings.HasPrefix(rendered, "5 throttling events.") {
+		t.Errorf("renderThrottlingIssues =\n%s", rendered)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TLSIssue represents a group of identical TLS or certificate errors
+type TLSIssue struct {
+	Kind    string
+	Subject string // Certificate subject or the names it is valid for, when present
+	Issuer  string // Certificate issuer or candidate authority, when present
+	Count   int
+	Example string
+}
+
+// tlsErrorKinds maps Go crypto/tls, OpenSSL and Java TLS errors to a kind, most specific first
+var tlsErrorKinds = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"certificate expired", regexp.MustCompile(`(?i)certificate has expired|certificate is not yet valid|CertificateExpiredException|certificate expired`)},
+	{"hostname mismatch", regexp.MustCompile(`(?i)certificate is valid for .*, not |doesn't match any of the subject alternative names|Hostname mismatch|hostname .* doesn't match`)},
+	{"self-signed certificate", regexp.MustCompile(`(?i)self[- ]signed certificate`)},
+	{"unknown authority", regexp.MustCompile(`(?i)certificate signed by unknown authority|unable to get local issuer certificate|PKIX path building failed|unable to find valid certification path`)},
+	{"bad certificate", regexp.MustCompile(`(?i)tls: bad certificate|bad_certificate|certificate required`)},
+	{"protocol mismatch", regexp.MustCompile(`(?i)wrong version number|first record does not look like a TLS handshake|unsupported protocol|no protocols available`)},
+	{"handshake failure", regexp.MustCompile(`(?i)tls: handshake failure|handshake_failure|SSLHandshakeException|TLS handshake (?:error|timeout)`)},
+	{"verification failed", regexp.MustCompile(`(?i)certificate verify failed|tls: failed to verify certificate|x509: `)},
+}
+
+var (
+	// "x509: certificate is valid for a, b, not c"
+	tlsValidForRegex = regexp.MustCompile(`certificate is valid for (.+?), not ([^\s,;)]+)`)
+
+	// OpenSSL style "subject=CN = foo" or "subject: CN=foo"
+	tlsSubjectRegex = regexp.MustCompile(`(?i)\bsubject\s*[=:]\s*['"]?([^'"\n;]+?)['"]?(?:[;)]|\s+issuer|$)`)
+	tlsIssuerRegex  = regexp.MustCompile(`(?i)\bissuer\s*[=:]\s*['"]?([^'"\n;]+?)['"]?(?:[;)]|\s+subject|$)`)
+
+	// Go's "while trying to verify candidate authority certificate "kubernetes""
+	tlsCandidateAuthorityRegex = regexp.MustCompile(`candidate authority certificate ['"]([^'"]+)['"]`)
+)
+
+// Function to extract TLS and certificate errors from the log content, grouped by kind, subject and issuer
+func extractTLSIssues(content string) []TLSIssue {
+	var issues []TLSIssue
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(content, "\n") {
+		kind := ""
+		for _, errorKind := range tlsErrorKinds {
+			if errorKind.re.MatchString(line) {
+				kind = errorKind.kind
+				break
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		subject, issuer := extractCertificateNames(line)
+		key := kind + "|" + subject + "|" + issuer
+		if i, ok := index[key]; ok {
+			issues[i].Count++
+			continue
+		}
+
+		index[key] = len(issues)
+		issues = append(issues, TLSIssue{Kind: kind, Subject: subject, Issuer: issuer, Count: 1, Example: line})
+	}
+
+	return issues
+}
+
+// Helper function to extract the certificate subject and issuer from a TLS error, when present
+func extractCertificateNames(line string) (string, string) {
+	var subject, issuer string
+
+	if matches := tlsValidForRegex.FindStringSubmatch(line); matches != nil {
+		subject = fmt.Sprintf("valid for %s (requested %s)", matches[1], matches[2])
+	} else if matches := tlsSubjectRegex.FindStringSubmatch(line); matches != nil {
+		subject = strings.TrimSpace(matches[1])
+	}
+
+	if matches := tlsIssuerRegex.FindStringSubmatch(line); matches != nil {
+		issuer = strings.TrimSpace(matches[1])
+	} else if matches := tlsCandidateAuthorityRegex.FindStringSubmatch(line); matches != nil {
+		issuer = matches[1]
+	}
+
+	return subject, issuer
+}
+
+// Function to render the TLS issues as a Markdown table
+func renderTLSIssues(issues []TLSIssue) string {
+	var sb strings.Builder
+	sb.WriteString("| Kind | Subject | Issuer | Occurrences | Example |\n")
+	sb.WriteString("|------|---------|--------|-------------|---------|\n")
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | `%s` |\n",
+			issue.Kind, tableCell(valueOrDash(issue.Subject), 80), tableCell(valueOrDash(issue.Issuer), 80),
+			issue.Count, tableCell(issue.Example, 160)))
+	}
+	return sb.String()
+}
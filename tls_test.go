@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTLSIssues(t *testing.T) {
+	content := `Get "https://api.shop.svc:443/health": x509: certificate has expired or is not yet valid: current time 2024-01-02T15:04:05Z is after 2023-12-31T23:59:59Z
+Get "https://10.0.0.5/": x509: certificate is valid for api.shop.svc, api.shop.svc.cluster.local, not 10.0.0.5
+x509: certificate signed by unknown authority (possibly because of "crypto/rsa: verification error" while trying to verify candidate authority certificate "kubernetes")
+x509: certificate signed by unknown authority (possibly because of "crypto/rsa: verification error" while trying to verify candidate authority certificate "kubernetes")
+SSL routines:ssl3_get_record:wrong version number
+verify error:num=18:self signed certificate subject=CN = registry.local; issuer=CN = registry.local
+http: TLS handshake error from 10.0.0.9:51234: remote error: tls: bad certificate`
+
+	issues := extractTLSIssues(content)
+	for i := range issues {
+		issues[i].Example = "" // Only the grouping is checked here
+	}
+	want := []TLSIssue{
+		{Kind: "certificate expired", Count: 1},
+		{Kind: "hostname mismatch", Subject: "valid for api.shop.svc, api.shop.svc.cluster.local (requested 10.0.0.5)", Count: 1},
+		{Kind: "unknown authority", Issuer: "kubernetes", Count: 2},
+		{Kind: "protocol mismatch", Count: 1},
+		{Kind: "self-signed certificate", Subject: "CN = registry.local", Issuer: "CN = registry.local", Count: 1},
+		{Kind: "bad certificate", Count: 1},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("extractTLSIssues =\n%+v\nwant\n%+v", issues, want)
+	}
+}
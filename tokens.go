@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Approximate number of characters per token for English text and logs
+const charsPerToken = 4
+
+// Approximate per-message overhead for role and formatting tokens
+const tokensPerMessage = 4
+
+// Function to estimate the number of tokens in a text without a tokenizer
+func estimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Function to estimate the number of prompt tokens for a list of messages
+func estimateMessagesTokens(messages []Message) int {
+	total := 0
+	for _, message := range messages {
+		total += tokensPerMessage + estimateTokens(message.Content)
+	}
+	return total
+}
+
+// TokenComponent is the estimated size of one component of the prompts, set with -token-breakdown
+type TokenComponent struct {
+	Component string `json:"component"`
+	Tokens    int    `json:"tokens"`
+}
+
+// Function to collect the prompt components, leaving out those absent from this run
+func tokenBreakdown(components ...TokenComponent) []TokenComponent {
+	var breakdown []TokenComponent
+	for _, component := range components {
+		if component.Tokens > 0 {
+			breakdown = append(breakdown, component)
+		}
+	}
+	return breakdown
+}
+
+// Helper function to compute the share of a component in the breakdown, as a percentage
+func tokenShare(tokens int, breakdown []TokenComponent) float64 {
+	total := 0
+	for _, component := range breakdown {
+		total += component.Tokens
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(tokens) * 100 / float64(total)
+}
+
+// Function to summarize the breakdown on one line for verbose output
+func summarizeTokenBreakdown(breakdown []TokenComponent) string {
+	var parts []string
+	for _, component := range breakdown {
+		parts = append(parts, fmt.Sprintf("%s ~%d (%.0f%%)", component.Component, component.Tokens, tokenShare(component.Tokens, breakdown)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Function to render the breakdown as a Markdown table
+func renderTokenBreakdown(breakdown []TokenComponent) string {
+	var sb strings.Builder
+	sb.WriteString("| Component | Estimated Tokens | Share |\n")
+	sb.WriteString("|-----------|------------------|-------|\n")
+	for _, component := range breakdown {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %.1f%% |\n", component.Component, component.Tokens, tokenShare(component.Tokens, breakdown)))
+	}
+	return sb.String()
+}
+
+// KeyPointsInput holds the sections of the key points input, which share the -context-percent budget
+type KeyPointsInput struct {
+	Introduction string // Instructions for the sections, e.g. about line numbers, never truncated
+	Primary      string // The -log content, possibly with line numbers
+	Related      string // The -also-log sections
+	Loki         string // The -loki-prepend context
+}
+
+// Function to assemble the input sent for the key points, the Loki context leading after the introduction
+func (in KeyPointsInput) String() string {
+	return in.Introduction + in.Loki + in.Primary + in.Related
+}
+
+// Function to fit the sections within the token budget by priority: the primary log first, then
+// the related logs, then the Loki context. Each section keeps its most recent lines, so a lower
+// priority section is cut before any line of a higher priority one is dropped. The introduction is always kept.
+func (in KeyPointsInput) Fit(budget int) KeyPointsInput {
+	remaining := budget - estimateTokens(in.Introduction)
+	fit := func(name, section string) string {
+		if section == "" {
+			return ""
+		}
+		if estimateTokens(section) <= remaining {
+			remaining -= estimateTokens(section)
+			return section
+		}
+
+		// A truncated section spends the rest of the budget
+		lines, dropped := truncateToTokenBudget(strings.Split(section, "\n"), remaining)
+		fmt.Fprintf(os.Stderr, "Warning: %s truncated to fit -context-percent, dropped the %d oldest lines\n", name, dropped)
+		remaining = 0
+		return strings.Join(lines, "\n")
+	}
+
+	in.Primary = fit("primary log", in.Primary)
+	in.Related = fit("related logs", in.Related)
+	in.Loki = fit("Loki context", in.Loki)
+	return in
+}
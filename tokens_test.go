@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Helper function to build a section of numbered lines
+func numberedSection(prefix string, count int) string {
+	var sb strings.Builder
+	for i := 1; i <= count; i++ {
+		sb.WriteString(prefix + " line " + strings.Repeat("x", 20) + "\n")
+	}
+	return sb.String()
+}
+
+func TestKeyPointsInputFitKeepsPrimaryLog(t *testing.T) {
+	input := KeyPointsInput{
+		Primary: numberedSection("primary", 10),
+		Related: numberedSection("related", 100),
+		Loki:    numberedSection("loki", 100),
+	}
+	budget := estimateTokens(input.Primary) + 50
+
+	fitted := input.Fit(budget)
+	if fitted.Primary != input.Primary {
+		t.Errorf("primary log was truncated:\n%s", fitted.Primary)
+	}
+	if fitted.Related == "" || len(fitted.Related) >= len(input.Related) {
+		t.Errorf("related logs were not truncated to the remaining budget (%d of %d bytes)", len(fitted.Related), len(input.Related))
+	}
+	if fitted.Loki != "" {
+		t.Errorf("Loki context kept %d bytes, want none once the budget is spent", len(fitted.Loki))
+	}
+	if tokens := estimateTokens(fitted.String()); tokens > budget {
+		t.Errorf("fitted input is ~%d tokens, over the budget of %d", tokens, budget)
+	}
+	if !strings.HasSuffix(fitted.Related, input.Related[len(input.Related)-len(fitted.Related):]) {
+		t.Errorf("related logs did not keep their most recent lines")
+	}
+}
+
+func TestKeyPointsInputFitTruncatesOversizedPrimaryLog(t *testing.T) {
+	input := KeyPointsInput{
+		Primary: "oldest\n" + numberedSection("primary", 100) + "newest",
+		Related: numberedSection("related", 10),
+	}
+
+	fitted := input.Fit(100)
+	if !strings.HasSuffix(fitted.Primary, "newest") || strings.Contains(fitted.Primary, "oldest") {
+		t.Errorf("primary log did not keep its most recent lines")
+	}
+	if fitted.Related != "" {
+		t.Errorf("related logs kept %d bytes, want none", len(fitted.Related))
+	}
+}
+
+func TestKeyPointsInputFitKeepsIntroduction(t *testing.T) {
+	input := KeyPointsInput{
+		Introduction: lineNumbersIntroduction,
+		Primary:      numberLines("oldest\n"+numberedSection("primary", 100)+"newest", 0),
+	}
+
+	fitted := input.Fit(100)
+	if fitted.Introduction != lineNumbersIntroduction || !strings.HasPrefix(fitted.String(), lineNumbersIntroduction) {
+		t.Errorf("introduction was truncated: %q", fitted.Introduction)
+	}
+	if strings.Contains(fitted.Primary, "oldest") || !strings.HasSuffix(fitted.Primary, "102: newest") {
+		t.Errorf("primary log did not keep its most recent numbered lines")
+	}
+	if tokens := estimateTokens(fitted.String()); tokens > 100 {
+		t.Errorf("fitted input is ~%d tokens, over the budget of 100", tokens)
+	}
+}
+
+func TestKeyPointsInputFitWithinBudgetIsUnchanged(t *testing.T) {
+	input := KeyPointsInput{Primary: "a\n", Related: "b\n", Loki: "c\n"}
+	if fitted := input.Fit(1000); fitted != input {
+		t.Errorf("Fit changed an input within budget: %+v", fitted)
+	}
+	if got := input.String(); got != "c\na\nb\n" {
+		t.Errorf("String() = %q, want the Loki context first", got)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TraceID represents a correlation or trace identifier found in the log content
+type TraceID struct {
+	Kind string `json:"kind"` // The format the ID was found in (trace_id, traceparent, request_id)
+	ID   string `json:"id"`
+}
+
+// traceIDPatterns lists the supported trace ID formats in the order they are matched
+var traceIDPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	// W3C trace context: traceparent: 00-<trace-id>-<parent-id>-<flags>
+	{"traceparent", regexp.MustCompile(`(?i)traceparent['"]?\s*[:=]\s*['"]?[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}`)},
+	// trace_id=..., traceId: "...", "trace.id": "..." and similar key/value forms
+	{"trace_id", regexp.MustCompile(`(?i)\btrace[_.\-]?id['"]?\s*[:=]\s*['"]?([0-9a-zA-Z\-]{8,64})`)},
+	// X-Request-ID headers and request_id fields
+	{"request_id", regexp.MustCompile(`(?i)\b(?:x-)?request[_\-]?id['"]?\s*[:=]\s*['"]?([0-9a-zA-Z\-]{8,64})`)},
+}
+
+// Function to extract distinct trace and correlation IDs from the log content
+func extractTraceIDs(content string) []TraceID {
+	var traceIDs []TraceID
+	seen := make(map[string]bool)
+
+	for _, pattern := range traceIDPatterns {
+		matches := pattern.re.FindAllStringSubmatch(content, -1)
+		for _, match := range matches {
+			if len(match) < 2 {
+				continue
+			}
+
+			// Normalize hex IDs so the same trace is only reported once
+			id := strings.ToLower(match[1])
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			traceIDs = append(traceIDs, TraceID{Kind: pattern.kind, ID: id})
+		}
+	}
+
+	return traceIDs
+}
+
+// Helper function to build a trace link from the configured base URL.
+// The base URL may contain a {trace_id} placeholder (e.g. for Grafana Explore);
+// otherwise the ID is appended as a path segment (e.g. Jaeger's /trace/<id>).
+func buildTraceLink(baseURL, id string) string {
+	if baseURL == "" {
+		return ""
+	}
+	if strings.Contains(baseURL, "{trace_id}") {
+		return strings.ReplaceAll(baseURL, "{trace_id}", id)
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + id
+}
+
+// Function to render the trace IDs as a Markdown section
+func renderTraceIDs(traceIDs []TraceID, baseURL string) string {
+	var sb strings.Builder
+
+	sb.WriteString("| Kind | ID |")
+	if baseURL != "" {
+		sb.WriteString(" Link |")
+	}
+	sb.WriteString("\n|------|----|")
+	if baseURL != "" {
+		sb.WriteString("------|")
+	}
+	sb.WriteString("\n")
+
+	for _, traceID := range traceIDs {
+		sb.WriteString(fmt.Sprintf("| %s | `%s` |", traceID.Kind, traceID.ID))
+		if baseURL != "" {
+			sb.WriteString(fmt.Sprintf(" [open](%s) |", buildTraceLink(baseURL, traceID.ID)))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
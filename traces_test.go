@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTraceIDs(t *testing.T) {
+	content := `level=info msg="handled" trace_id=4BF92F3577B34DA6A3CE929D0E0E4736
+GET /checkout traceparent: 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01
+{"msg":"retry","traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}
+X-Request-ID: 7c1d9e4a-2b3f-4c5d-8e6f-0a1b2c3d4e5f
+request_id=short`
+
+	want := []TraceID{
+		{Kind: "traceparent", ID: "0af7651916cd43dd8448eb211c80319c"},
+		{Kind: "trace_id", ID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Kind: "request_id", ID: "7c1d9e4a-2b3f-4c5d-8e6f-0a1b2c3d4e5f"},
+	}
+	if got := extractTraceIDs(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("extractTraceIDs = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildTraceLink(t *testing.T) {
+	tests := []struct {
+		baseURL, want string
+	}{
+		{"", ""},
+		{"https://jaeger.example.com/trace/", "https://jaeger.example.com/trace/abc123"},
+		{"https://grafana.example.com/explore?trace={trace_id}", "https://grafana.example.com/explore?trace=abc123"},
+	}
+	for _, tt := range tests {
+		if got := buildTraceLink(tt.baseURL, "abc123"); got != tt.want {
+			t.Errorf("buildTraceLink(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// A markdown table row followed by a header separator row (e.g. "|---|:---:|")
+	markdownTableRegex = regexp.MustCompile(`(?m)^\s*\|.*\|\s*\n\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+
+	// A heading or bold label mentioning recommendations
+	recommendationsHeadingRegex = regexp.MustCompile(`(?im)^\s*(#{1,6}\s+|\*\*|__)?.*recommendation`)
+
+	// A bullet or numbered list item
+	listItemRegex = regexp.MustCompile(`(?m)^\s*([-*+]|\d+[.)])\s+\S`)
+)
+
+// Function to check that an analysis contains the sections requested by the system prompt.
+// It returns a description of each missing section; an empty result means the output is valid.
+func validateAnalysisOutput(analysis string) []string {
+	var missing []string
+
+	if !markdownTableRegex.MatchString(analysis) {
+		missing = append(missing, "at least one markdown table")
+	}
+
+	// The recommendations list must follow a recommendations heading
+	loc := recommendationsHeadingRegex.FindStringIndex(analysis)
+	if loc == nil || !listItemRegex.MatchString(analysis[loc[1]:]) {
+		missing = append(missing, "a recommendations section with a bulleted or numbered list")
+	}
+
+	return missing
+}
+
+// Helper function to build the follow-up prompt asking the model to comply with the required structure
+func buildValidationRetryPrompt(missing []string) string {
+	var sb strings.Builder
+	sb.WriteString("Your previous response is missing the following required elements:\n\n")
+	for _, item := range missing {
+		sb.WriteString(fmt.Sprintf("- %s\n", item))
+	}
+	sb.WriteString("\nPlease provide the complete analysis again, keeping all of its content and including every missing element.")
+	return sb.String()
+}
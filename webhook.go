@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// WebhookOptions holds where each completed analysis is delivered
+type WebhookOptions struct {
+	URL     string
+	Headers map[string]string
+}
+
+// Function to parse the -webhook URL and the repeatable -webhook-header "Name: value" flags
+func parseWebhookOptions(webhookURL string, headerValues []string) (WebhookOptions, error) {
+	if webhookURL == "" {
+		if len(headerValues) > 0 {
+			return WebhookOptions{}, fmt.Errorf("Error: -webhook-header requires -webhook")
+		}
+		return WebhookOptions{}, nil
+	}
+
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return WebhookOptions{}, fmt.Errorf("Error: -webhook must be an http or https URL, got %q", webhookURL)
+	}
+
+	headers, err := parseHeaders("webhook-header", headerValues)
+	if err != nil {
+		return WebhookOptions{}, err
+	}
+	headers["Content-Type"] = "application/json"
+	return WebhookOptions{URL: webhookURL, Headers: headers}, nil
+}
+
+// Function to POST the report as JSON to the webhook, redacted like the written outputs,
+// retrying failures with the request retry policy.
+// Delivery is best effort: the analysis is already saved, so a failure is only reported as a warning.
+func deliverWebhook(ctx context.Context, webhook WebhookOptions, requestOptions RequestOptions, report AnalysisReport) {
+	if webhook.URL == "" {
+		return
+	}
+
+	payload, err := report.Render("json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: webhook delivery for %s failed: %v\n", report.LogFile, err)
+		return
+	}
+	payload = []byte(redactText(string(payload), requestOptions.Redactions))
+
+	// Reuse the API request retries, with the webhook's own URL and headers.
+	// The retries log only covers API requests.
+	webhookOptions := requestOptions
+	webhookOptions.URL = webhook.URL
+	webhookOptions.Headers = webhook.Headers
+	webhookOptions.RetriesLog = ""
+
+	resp, err := postWithRetries(ctx, webhookOptions, payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: webhook delivery for %s failed: %v\n", report.LogFile, err)
+		return
+	}
+	resp.Body.Close()
+	verbosef("Webhook delivered analysis of %s (status %d)", report.LogFile, resp.StatusCode)
+}